@@ -0,0 +1,348 @@
+package headers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseQuality parses a qvalue: a number between 0 and 1 with at most three
+// digits after the decimal point.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-12.4.2
+func parseQuality(s string) (float64, error) {
+	if len(s) == 0 || len(s) > 5 || s[0] != '0' && s[0] != '1' || len(s) > 1 && s[1] != '.' {
+		return 0, fmt.Errorf("invalid qvalue %s", s)
+	}
+	if strings.Trim(s[min(2, len(s)):], "0123456789") != "" {
+		return 0, fmt.Errorf("invalid qvalue %s", s)
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q > 1 {
+		return 0, fmt.Errorf("invalid qvalue %s", s)
+	}
+	return q, nil
+}
+
+func formatQuality(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// A weighted is a member of an Accept style list: a value, its parameters
+// and its quality weight.
+type weighted struct {
+	value  string
+	params map[string]string
+	q      float64
+}
+
+// parseWeightedList parses a comma-separated list of values, each optionally
+// followed by parameters and a "q" weight. Parameters after the weight are
+// extensions and are dropped.
+func parseWeightedList(name, hdr string) ([]weighted, error) {
+	var list []weighted
+	for _, member := range splitList(hdr) {
+		w := weighted{value: member, q: 1}
+		if semi := strings.IndexByte(member, ';'); semi >= 0 {
+			w.value = member[:semi]
+			params, err := parseDirectiveList(member[semi+1:], ';')
+			if err != nil {
+				return nil, fmt.Errorf("Invalid parameters in %s: %s", name, member)
+			}
+			for _, p := range params {
+				key := strings.ToLower(p.name)
+				if key == "q" {
+					if w.q, err = parseQuality(p.value); err != nil {
+						return nil, fmt.Errorf("Invalid weight in %s: %s", name, member)
+					}
+					break
+				}
+				if w.params == nil {
+					w.params = map[string]string{}
+				}
+				w.params[key] = p.value
+			}
+		}
+		w.value = strings.TrimSpace(w.value)
+		list = append(list, w)
+	}
+	return list, nil
+}
+
+// formatWeighted formats a list member, omitting the weight when it is 1.
+func formatWeighted(value string, params map[string]string, q float64) string {
+	var b strings.Builder
+	b.WriteString(value)
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(";" + k + "=" + quoteIfNeeded(params[k]))
+	}
+	if q != 1 {
+		b.WriteString(";q=" + formatQuality(q))
+	}
+	return b.String()
+}
+
+// quoteIfNeeded returns a parameter value as a token if possible, or as a
+// quoted string.
+func quoteIfNeeded(v string) string {
+	if v != "" && strings.IndexFunc(v, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r)
+	}) < 0 {
+		return v
+	}
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(v) + "\""
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// A MediaRange is a member of an Accept header: a media type, possibly with
+// wildcards, and a weight.
+type MediaRange struct {
+	// The top-level type, such as "text", or "*".
+	Type string
+	// The subtype, such as "html", or "*".
+	Subtype string
+	// Media type parameters, keyed by their lower-cased name.
+	Params map[string]string
+	// The relative preference for the range, between 0 and 1. A weight of 0
+	// means "not acceptable".
+	Q float64
+}
+
+func (mr MediaRange) String() string {
+	return formatWeighted(mr.Type+"/"+mr.Subtype, mr.Params, mr.Q)
+}
+
+// match reports whether the range includes a media type, and how
+// specifically: the result is -1 for no match, and higher for more specific
+// matches.
+func (mr MediaRange) match(typ, subtype string, params map[string]string) int {
+	switch {
+	case mr.Type == "*" && mr.Subtype == "*":
+		return 0
+	case !strings.EqualFold(mr.Type, typ):
+		return -1
+	case mr.Subtype == "*":
+		return 1
+	case !strings.EqualFold(mr.Subtype, subtype):
+		return -1
+	}
+	for k, v := range mr.Params {
+		if !strings.EqualFold(params[k], v) {
+			return -1
+		}
+	}
+	return 2 + len(mr.Params)
+}
+
+// The Accept request HTTP header indicates which content types, expressed as
+// MIME types, the client is able to understand. The server uses content
+// negotiation to select one of the proposals and informs the client of the
+// choice with the Content-Type response header.
+//
+// https://mdn.io/Accept
+type Accept struct {
+	Ranges []MediaRange
+}
+
+func (h Accept) Name() string {
+	return "Accept"
+}
+
+func (h Accept) Value() string {
+	v := make([]string, len(h.Ranges))
+	for i, mr := range h.Ranges {
+		v[i] = mr.String()
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *Accept) Parse(hdr string) error {
+	list, err := parseWeightedList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	val := Accept{}
+	for _, w := range list {
+		slash := strings.IndexByte(w.value, '/')
+		if slash <= 0 || slash == len(w.value)-1 {
+			return fmt.Errorf("Invalid media range in Accept: %s", w.value)
+		}
+		mr := MediaRange{
+			Type:    strings.ToLower(w.value[:slash]),
+			Subtype: strings.ToLower(w.value[slash+1:]),
+			Params:  w.params,
+			Q:       w.q,
+		}
+		if mr.Type == "*" && mr.Subtype != "*" {
+			return fmt.Errorf("Invalid media range in Accept: %s", w.value)
+		}
+		val.Ranges = append(val.Ranges, mr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &Accept{}
+
+// Quality returns the weight the header gives to a media type, such as
+// "text/html;level=1", taken from the most specific matching range. The
+// result is 0 if the media type is not acceptable.
+func (h Accept) Quality(mediaType string) float64 {
+	typ, subtype, params := splitMediaType(mediaType)
+	best, q := -1, 0.0
+	for _, mr := range h.Ranges {
+		if s := mr.match(typ, subtype, params); s > best {
+			best, q = s, mr.Q
+		}
+	}
+	return q
+}
+
+// splitMediaType splits a media type such as "text/html;level=1" into its
+// type, subtype and parameters.
+func splitMediaType(mediaType string) (string, string, map[string]string) {
+	params := map[string]string{}
+	if semi := strings.IndexByte(mediaType, ';'); semi >= 0 {
+		list, _ := parseDirectiveList(mediaType[semi+1:], ';')
+		for _, p := range list {
+			params[strings.ToLower(p.name)] = p.value
+		}
+		mediaType = mediaType[:semi]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	if slash := strings.IndexByte(mediaType, '/'); slash >= 0 {
+		return mediaType[:slash], mediaType[slash+1:], params
+	}
+	return mediaType, "", params
+}
+
+// Negotiator selects the representation to send in response to a request,
+// from those the server offers, by proactive content negotiation.
+//
+// https://mdn.io/HTTP/Content_negotiation
+type Negotiator struct {
+	// The media types the server can produce, such as "application/json", in
+	// order of preference.
+	ContentTypes []string
+	// The language tags the content is available in, such as "en-US", in
+	// order of preference. The first is the default.
+	Languages []string
+	// The content codings the server can apply, such as "gzip", in order of
+	// preference. The identity coding is always available.
+	Encodings []string
+	// The charsets the server can encode text in, such as "utf-8", in order
+	// of preference.
+	Charsets []string
+}
+
+// Negotiated is the outcome of content negotiation.
+type Negotiated struct {
+	// The selected media type, from the offered ContentTypes.
+	ContentType string
+	// The selected language tag, from the offered Languages.
+	Language string
+	// The selected content coding, from the offered Encodings, or
+	// "identity".
+	Encoding string
+	// The selected charset, from the offered Charsets.
+	Charset string
+	// The request headers which influenced the choice, to be added to the
+	// Vary header of the response.
+	Vary []string
+}
+
+// Negotiate picks the offers that best match the request's preferences.
+//
+// Each media type is weighted by the most specific range that matches it,
+// and ties go to the offer listed first. Requests without a valid Accept
+// header accept any media type. The status code is 200 (OK), or 406 (Not
+// Acceptable) if the request accepts none of the media types.
+//
+// The language is chosen by RFC 4647 lookup and falls back to the first
+// offer, as sending content in an unrequested language is usually better
+// than sending an error. The content coding is chosen by NegotiateEncoding,
+// and the status code is also 406 if the request accepts no coding at all.
+//
+// Charsets are negotiated like media types, with the same fallback for
+// requests without a valid Accept-Charset header, and the same 406 when
+// none of the offers is acceptable.
+func (n Negotiator) Negotiate(r *http.Request) (Negotiated, int) {
+	var result Negotiated
+	status := http.StatusOK
+	if len(n.ContentTypes) > 0 {
+		var accept Accept
+		result.Vary = append(result.Vary, accept.Name())
+		hdr := r.Header.Get(accept.Name())
+		if hdr == "" || accept.Parse(hdr) != nil {
+			result.ContentType = n.ContentTypes[0]
+		} else if result.ContentType = best(n.ContentTypes, accept.Quality); result.ContentType == "" {
+			status = http.StatusNotAcceptable
+		}
+	}
+	if len(n.Languages) > 0 {
+		var accept AcceptLanguage
+		result.Vary = append(result.Vary, accept.Name())
+		result.Language = n.Languages[0]
+		if err := accept.Parse(r.Header.Get(accept.Name())); err == nil {
+			result.Language = accept.Lookup(n.Languages, result.Language)
+		}
+	}
+	if len(n.Encodings) > 0 {
+		var ok bool
+		result.Vary = append(result.Vary, AcceptEncoding{}.Name())
+		if result.Encoding, ok = NegotiateEncoding(r, n.Encodings...); !ok {
+			status = http.StatusNotAcceptable
+		}
+	}
+	if len(n.Charsets) > 0 {
+		var accept AcceptCharset
+		result.Vary = append(result.Vary, accept.Name())
+		hdr := r.Header.Get(accept.Name())
+		if hdr == "" || accept.Parse(hdr) != nil {
+			result.Charset = n.Charsets[0]
+		} else if result.Charset = best(n.Charsets, accept.Quality); result.Charset == "" {
+			status = http.StatusNotAcceptable
+		}
+	}
+	return result, status
+}
+
+// MediaType returns the selected media type, with a charset parameter for
+// the selected charset, if any, for use as the response's Content-Type.
+func (n Negotiated) MediaType() string {
+	if n.Charset == "" || n.ContentType == "" {
+		return n.ContentType
+	}
+	typ, subtype, params := splitMediaType(n.ContentType)
+	params["charset"] = n.Charset
+	if v := mime.FormatMediaType(typ+"/"+subtype, params); v != "" {
+		return v
+	}
+	return n.ContentType
+}
+
+// best returns the offer with the highest non-zero quality, preferring
+// earlier offers on ties.
+func best(offers []string, quality func(string) float64) string {
+	choice, q := "", 0.0
+	for _, offer := range offers {
+		if oq := quality(offer); oq > q {
+			choice, q = offer, oq
+		}
+	}
+	return choice
+}
@@ -0,0 +1,109 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAccept(t *testing.T) {
+	verify(t, []testcase{
+		{&Accept{Ranges: []MediaRange{{Type: "text", Subtype: "html", Q: 1}}}, "text/html"},
+		{&Accept{Ranges: []MediaRange{
+			{Type: "application", Subtype: "json", Q: 1},
+			{Type: "text", Subtype: "html", Params: map[string]string{"level": "1"}, Q: 0.5},
+			{Type: "*", Subtype: "*", Q: 0.001},
+		}}, "application/json, text/html;level=1;q=0.5, */*;q=0.001"},
+		{&Accept{Ranges: []MediaRange{{Type: "text", Subtype: "*", Params: map[string]string{"charset": "a b"}, Q: 0}}}, `text/*;charset="a b";q=0`},
+	})
+}
+
+func TestAcceptParse(t *testing.T) {
+	var h Accept
+	if err := h.Parse(`Text/HTML;Level="1" ;q=0.7;ext=x, application/cbor`); err != nil {
+		t.Fatal(err)
+	}
+	expected := []MediaRange{
+		{Type: "text", Subtype: "html", Params: map[string]string{"level": "1"}, Q: 0.7},
+		{Type: "application", Subtype: "cbor", Q: 1},
+	}
+	if !reflect.DeepEqual(h.Ranges, expected) {
+		t.Errorf("unexpected ranges %+v", h.Ranges)
+	}
+
+	for _, hdr := range []string{
+		"text",
+		"*/html",
+		"text/",
+		"text/html;q=1.5",
+		"text/html;q=0.1234",
+		"text/html;q=.5",
+		"text/html;q=1.001",
+		"text/html;q=",
+	} {
+		if err := h.Parse(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+	for _, hdr := range []string{"text/html;q=1.000", "text/html;q=0", "text/html;q=0.", "text/html;q=1"} {
+		if err := h.Parse(hdr); err != nil {
+			t.Errorf("unexpected error parsing %q: %s", hdr, err)
+		}
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	var h Accept
+	h.Parse("text/*;q=0.3, text/html;q=0.7, text/html;level=1, text/html;level=2;q=0.4, */*;q=0.5")
+	for mediaType, q := range map[string]float64{
+		"text/html;level=1": 1,
+		"text/html":         0.7,
+		"text/plain":        0.3,
+		"image/jpeg":        0.5,
+		"text/html;level=2": 0.4,
+		"text/html;level=3": 0.7,
+	} {
+		if got := h.Quality(mediaType); got != q {
+			t.Errorf("expected %s to have quality %v, got %v", mediaType, q, got)
+		}
+	}
+	if q := (Accept{}).Quality("text/html"); q != 0 {
+		t.Errorf("expected empty Accept to accept nothing, got %v", q)
+	}
+}
+
+func TestNegotiator(t *testing.T) {
+	n := Negotiator{ContentTypes: []string{"application/json", "application/cbor", "text/html"}}
+	for _, c := range []struct {
+		accept, expected string
+		status           int
+	}{
+		{"", "application/json", http.StatusOK},
+		{"invalid", "application/json", http.StatusOK},
+		{"*/*", "application/json", http.StatusOK},
+		{"text/html", "text/html", http.StatusOK},
+		{"application/*, application/json;q=0.5", "application/cbor", http.StatusOK},
+		{"text/html;q=0.9, */*;q=0.8", "text/html", http.StatusOK},
+		{"application/cbor, application/json", "application/json", http.StatusOK},
+		{"*/*, application/json;q=0", "application/cbor", http.StatusOK},
+		{"image/png", "", http.StatusNotAcceptable},
+		{"text/html;q=0", "", http.StatusNotAcceptable},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if c.accept != "" {
+			r.Header.Set("Accept", c.accept)
+		}
+		result, status := n.Negotiate(r)
+		if result.ContentType != c.expected || status != c.status {
+			t.Errorf("%q: expected %q %d, got %q %d", c.accept, c.expected, c.status, result.ContentType, status)
+		}
+		if !reflect.DeepEqual(result.Vary, []string{"Accept"}) {
+			t.Errorf("%q: unexpected Vary %v", c.accept, result.Vary)
+		}
+	}
+
+	if result, status := (Negotiator{}).Negotiate(httptest.NewRequest("GET", "/", nil)); status != http.StatusOK || result.Vary != nil {
+		t.Errorf("expected nothing to negotiate, got %+v %d", result, status)
+	}
+}
@@ -0,0 +1,476 @@
+package headers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Cache-Control HTTP header holds directives (instructions) for caching in
+// both requests and responses. CacheControl models the directives a server
+// sends with a response.
+//
+// https://mdn.io/Cache-Control
+type CacheControl struct {
+	// The maximum amount of time a resource is considered fresh. Unlike
+	// Expires, this directive is relative to the time of the request.
+	MaxAge *time.Duration
+	// Overrides MaxAge or the Expires header, but only for shared caches (e.g.,
+	// proxies). Ignored by private caches.
+	SMaxAge *time.Duration
+	// A cache will send the request to the origin server for validation
+	// before releasing a cached copy.
+	NoCache bool
+	// Restricts NoCache to the listed header fields. The rest of the response
+	// may be reused without validation.
+	NoCacheFields []string
+	// The cache should not store anything about the client request or server
+	// response.
+	NoStore bool
+	// No transformations or conversions should be made to the resource.
+	NoTransform bool
+	// Once a resource becomes stale, caches must not use their stale copy
+	// without successful validation on the origin server.
+	MustRevalidate bool
+	// Like MustRevalidate, but only for shared caches.
+	ProxyRevalidate bool
+	// Caches should only store the response if they understand the
+	// requirements for caching based on its status code.
+	MustUnderstand bool
+	// The response is intended for a single user and must not be stored by a
+	// shared cache.
+	Private bool
+	// Restricts Private to the listed header fields.
+	PrivateFields []string
+	// The response may be stored by any cache, even if the response is
+	// normally non-cacheable.
+	Public bool
+	// Indicates that the response body will not change over time.
+	Immutable bool
+	// Indicates the client will accept a stale response, while asynchronously
+	// checking in the background for a fresh one.
+	StaleWhileRevalidate *time.Duration
+	// Indicates the client will accept a stale response if the check for a
+	// fresh one fails.
+	StaleIfError *time.Duration
+}
+
+func (h CacheControl) Name() string {
+	return "Cache-Control"
+}
+
+func (h CacheControl) Value() string {
+	var v []string
+	if h.Public {
+		v = append(v, "public")
+	}
+	if h.Private {
+		v = append(v, fieldListDirective("private", h.PrivateFields))
+	}
+	if h.NoCache {
+		v = append(v, fieldListDirective("no-cache", h.NoCacheFields))
+	}
+	if h.NoStore {
+		v = append(v, "no-store")
+	}
+	if h.NoTransform {
+		v = append(v, "no-transform")
+	}
+	if h.MustRevalidate {
+		v = append(v, "must-revalidate")
+	}
+	if h.ProxyRevalidate {
+		v = append(v, "proxy-revalidate")
+	}
+	if h.MustUnderstand {
+		v = append(v, "must-understand")
+	}
+	if h.MaxAge != nil {
+		v = append(v, deltaDirective("max-age", *h.MaxAge))
+	}
+	if h.SMaxAge != nil {
+		v = append(v, deltaDirective("s-maxage", *h.SMaxAge))
+	}
+	if h.Immutable {
+		v = append(v, "immutable")
+	}
+	if h.StaleWhileRevalidate != nil {
+		v = append(v, deltaDirective("stale-while-revalidate", *h.StaleWhileRevalidate))
+	}
+	if h.StaleIfError != nil {
+		v = append(v, deltaDirective("stale-if-error", *h.StaleIfError))
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *CacheControl) Parse(hdr string) error {
+	directives, err := parseCacheDirectives(hdr)
+	if err != nil {
+		return err
+	}
+	val := CacheControl{}
+	for _, d := range directives {
+		switch d.name {
+		case "max-age":
+			val.MaxAge, err = parseDeltaDirective(d)
+		case "s-maxage":
+			val.SMaxAge, err = parseDeltaDirective(d)
+		case "no-cache":
+			val.NoCache = true
+			val.NoCacheFields = parseFieldList(d.value)
+		case "no-store":
+			val.NoStore = true
+		case "no-transform":
+			val.NoTransform = true
+		case "must-revalidate":
+			val.MustRevalidate = true
+		case "proxy-revalidate":
+			val.ProxyRevalidate = true
+		case "must-understand":
+			val.MustUnderstand = true
+		case "private":
+			val.Private = true
+			val.PrivateFields = parseFieldList(d.value)
+		case "public":
+			val.Public = true
+		case "immutable":
+			val.Immutable = true
+		case "stale-while-revalidate":
+			val.StaleWhileRevalidate, err = parseDeltaDirective(d)
+		case "stale-if-error":
+			val.StaleIfError, err = parseDeltaDirective(d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if val.Public && val.Private {
+		return fmt.Errorf("Cache-Control directives public and private conflict; got %s", hdr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &CacheControl{}
+
+// parseCacheDirectives splits a Cache-Control style header into lower-cased
+// directives, rejecting any directive that appears more than once.
+func parseCacheDirectives(hdr string) ([]directive, error) {
+	directives, err := parseDirectiveList(hdr, ',')
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for i := range directives {
+		name := strings.ToLower(directives[i].name)
+		if seen[name] {
+			return nil, fmt.Errorf("Duplicate Cache-Control directive %s; got %s", name, hdr)
+		}
+		seen[name] = true
+		directives[i].name = name
+	}
+	return directives, nil
+}
+
+// The largest delta-seconds value a cache is required to represent. Larger
+// values are treated as this value.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-1.2.2
+const maxDeltaSeconds = math.MaxInt32
+
+func parseDeltaSeconds(value string) (time.Duration, error) {
+	if value == "" || strings.Trim(value, "0123456789") != "" {
+		return 0, fmt.Errorf("delta-seconds must be a non-negative integer; got %s", value)
+	}
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || secs > maxDeltaSeconds {
+		secs = maxDeltaSeconds
+	}
+	return time.Duration(secs) * time.Second, nil
+}
+
+func parseDeltaDirective(d directive) (*time.Duration, error) {
+	delta, err := parseDeltaSeconds(d.value)
+	if err != nil {
+		return nil, fmt.Errorf("The value for %s must be a non-negative integer; got %s", d.name, d.value)
+	}
+	return &delta, nil
+}
+
+func deltaDirective(name string, d time.Duration) string {
+	return name + "=" + strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func fieldListDirective(name string, fields []string) string {
+	if len(fields) == 0 {
+		return name
+	}
+	return name + "=\"" + strings.Join(fields, ", ") + "\""
+}
+
+func parseFieldList(value string) []string {
+	var fields []string
+	for _, f := range splitList(value) {
+		fields = append(fields, textproto.CanonicalMIMEHeaderKey(f))
+	}
+	return fields
+}
+
+// RequestCacheControl models the Cache-Control directives a client sends with
+// a request. They share a header name with CacheControl but have different
+// semantics.
+//
+// https://mdn.io/Cache-Control
+type RequestCacheControl struct {
+	// The client is unwilling to accept a response whose age is greater than
+	// this duration.
+	MaxAge *time.Duration
+	// The client will accept a stale response.
+	MaxStale bool
+	// If set, limits how stale a response the client will accept.
+	MaxStaleLimit *time.Duration
+	// The client wants a response that will still be fresh for at least this
+	// duration.
+	MinFresh *time.Duration
+	// The client does not want a stored response to be used without
+	// successful validation on the origin server.
+	NoCache bool
+	// The client asks caches not to store the request or the response.
+	NoStore bool
+	// No transformations or conversions should be made to the resource.
+	NoTransform bool
+	// The client only wishes to obtain a stored response. Caches that cannot
+	// satisfy the request should respond with a 504 (Gateway Timeout) status
+	// code.
+	OnlyIfCached bool
+}
+
+func (h RequestCacheControl) Name() string {
+	return "Cache-Control"
+}
+
+func (h RequestCacheControl) Value() string {
+	var v []string
+	if h.NoCache {
+		v = append(v, "no-cache")
+	}
+	if h.NoStore {
+		v = append(v, "no-store")
+	}
+	if h.NoTransform {
+		v = append(v, "no-transform")
+	}
+	if h.OnlyIfCached {
+		v = append(v, "only-if-cached")
+	}
+	if h.MaxAge != nil {
+		v = append(v, deltaDirective("max-age", *h.MaxAge))
+	}
+	if h.MaxStale {
+		if h.MaxStaleLimit != nil {
+			v = append(v, deltaDirective("max-stale", *h.MaxStaleLimit))
+		} else {
+			v = append(v, "max-stale")
+		}
+	}
+	if h.MinFresh != nil {
+		v = append(v, deltaDirective("min-fresh", *h.MinFresh))
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *RequestCacheControl) Parse(hdr string) error {
+	directives, err := parseCacheDirectives(hdr)
+	if err != nil {
+		return err
+	}
+	val := RequestCacheControl{}
+	for _, d := range directives {
+		switch d.name {
+		case "max-age":
+			val.MaxAge, err = parseDeltaDirective(d)
+		case "max-stale":
+			val.MaxStale = true
+			if d.value != "" {
+				val.MaxStaleLimit, err = parseDeltaDirective(d)
+			}
+		case "min-fresh":
+			val.MinFresh, err = parseDeltaDirective(d)
+		case "no-cache":
+			val.NoCache = true
+		case "no-store":
+			val.NoStore = true
+		case "no-transform":
+			val.NoTransform = true
+		case "only-if-cached":
+			val.OnlyIfCached = true
+		}
+		if err != nil {
+			return err
+		}
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &RequestCacheControl{}
+
+// Accepts reports whether a stored response may be used to satisfy the
+// request without contacting the origin server. The stored response is
+// described by its Cache-Control header, its current age and its freshness
+// lifetime.
+//
+// A false result means the cache must validate the stored response, or
+// respond with 504 (Gateway Timeout) if OnlyIfCached is set.
+func (h RequestCacheControl) Accepts(stored CacheControl, age, lifetime time.Duration) bool {
+	if h.NoCache || stored.NoCache && len(stored.NoCacheFields) == 0 {
+		return false
+	}
+	if h.MaxAge != nil && age > *h.MaxAge {
+		return false
+	}
+	if h.MinFresh != nil && lifetime-age < *h.MinFresh {
+		return false
+	}
+	if staleness := age - lifetime; staleness >= 0 {
+		if !h.MaxStale || stored.MustRevalidate {
+			return false
+		}
+		if h.MaxStaleLimit != nil && staleness > *h.MaxStaleLimit {
+			return false
+		}
+	}
+	return true
+}
+
+// AcceptsShared is like Accepts, for a shared cache: the stored response's
+// s-maxage and proxy-revalidate directives also forbid serving it stale,
+// whatever max-stale the request allows.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-5.2.2.10
+func (h RequestCacheControl) AcceptsShared(stored CacheControl, age, lifetime time.Duration) bool {
+	if age >= lifetime && (stored.ProxyRevalidate || stored.SMaxAge != nil) {
+		return false
+	}
+	return h.Accepts(stored, age, lifetime)
+}
+
+// The Expires HTTP header contains the date/time after which the response is
+// considered expired. If there is a Cache-Control header with the max-age or
+// s-maxage directive in the response, the Expires header is ignored.
+//
+// https://mdn.io/Expires
+type Expires struct {
+	// The expiration time. The zero time means the response is already
+	// expired, and is sent as "0".
+	Time time.Time
+}
+
+func (h Expires) Name() string {
+	return "Expires"
+}
+
+func (h Expires) Value() string {
+	if h.Time.IsZero() {
+		return "0"
+	}
+	return formatHTTPDate(h.Time)
+}
+
+// Parse never fails: invalid dates, especially the value "0", represent a
+// time in the past.
+func (h *Expires) Parse(hdr string) error {
+	t, err := parseHTTPDate(h.Name(), hdr)
+	if err != nil {
+		t = time.Time{}
+	}
+	*h = Expires{t}
+	return nil
+}
+
+var _ Header = &Expires{}
+
+// The Pragma HTTP/1.0 general header is used for backwards compatibility with
+// HTTP/1.0 caches where the Cache-Control HTTP/1.1 header is not yet present.
+// It is only honored when there is no Cache-Control header.
+//
+// https://mdn.io/Pragma
+type Pragma struct {
+	// Forces caches to submit the request to the origin server for
+	// validation before releasing a cached copy.
+	NoCache bool
+}
+
+func (h Pragma) Name() string {
+	return "Pragma"
+}
+
+func (h Pragma) Value() string {
+	if h.NoCache {
+		return "no-cache"
+	}
+	return ""
+}
+
+func (h *Pragma) Parse(hdr string) error {
+	val := Pragma{}
+	for _, member := range splitList(hdr) {
+		if strings.EqualFold(member, "no-cache") {
+			val.NoCache = true
+		}
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &Pragma{}
+
+// fieldValue returns the combined value of a list-based header which may be
+// sent as several field lines, such as Cache-Control.
+func fieldValue(h http.Header, name string) string {
+	return strings.Join(h.Values(name), ", ")
+}
+
+// requestCacheControl returns the Cache-Control directives of a request,
+// falling back to Pragma: no-cache when Cache-Control is absent.
+func requestCacheControl(r *http.Request) RequestCacheControl {
+	var rcc RequestCacheControl
+	if hdr := fieldValue(r.Header, rcc.Name()); hdr != "" {
+		rcc.Parse(hdr)
+		return rcc
+	}
+	var pragma Pragma
+	pragma.Parse(r.Header.Get(pragma.Name()))
+	rcc.NoCache = pragma.NoCache
+	return rcc
+}
+
+// NeverCache sets the Cache-Control, Expires and Pragma headers of a
+// response so that no cache, including HTTP/1.0 ones, stores or reuses it.
+func NeverCache(w http.ResponseWriter) {
+	zero := time.Duration(0)
+	for _, h := range []Header{
+		&CacheControl{NoCache: true, NoStore: true, MustRevalidate: true, MaxAge: &zero},
+		&Expires{},
+		&Pragma{NoCache: true},
+	} {
+		w.Header().Set(h.Name(), h.Value())
+	}
+}
+
+// CacheFor sets the Cache-Control and Expires headers of a response so that
+// caches may reuse it for the given duration, and removes any Pragma header
+// that would contradict them.
+func CacheFor(w http.ResponseWriter, d time.Duration) {
+	for _, h := range []Header{
+		&CacheControl{MaxAge: &d},
+		&Expires{time.Now().Add(d)},
+	} {
+		w.Header().Set(h.Name(), h.Value())
+	}
+	w.Header().Del(Pragma{}.Name())
+}
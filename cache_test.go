@@ -0,0 +1,197 @@
+package headers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func seconds(n int) *time.Duration {
+	d := time.Duration(n) * time.Second
+	return &d
+}
+
+func TestCacheControl(t *testing.T) {
+	verify(t, []testcase{
+		{&CacheControl{}, ""},
+		{&CacheControl{NoStore: true}, "no-store"},
+		{&CacheControl{MaxAge: seconds(0)}, "max-age=0"},
+		{&CacheControl{Public: true, MaxAge: seconds(3600), Immutable: true},
+			"public, max-age=3600, immutable"},
+		{&CacheControl{Private: true, PrivateFields: []string{"Set-Cookie", "X-Token"}},
+			"private=\"Set-Cookie, X-Token\""},
+		{&CacheControl{NoCache: true, NoCacheFields: []string{"Set-Cookie"}},
+			"no-cache=\"Set-Cookie\""},
+		{&CacheControl{NoCache: true, NoTransform: true, MustRevalidate: true, ProxyRevalidate: true, MustUnderstand: true},
+			"no-cache, no-transform, must-revalidate, proxy-revalidate, must-understand"},
+		{&CacheControl{MaxAge: seconds(60), SMaxAge: seconds(600), StaleWhileRevalidate: seconds(30), StaleIfError: seconds(86400)},
+			"max-age=60, s-maxage=600, stale-while-revalidate=30, stale-if-error=86400"},
+	})
+}
+
+func TestCacheControlParse(t *testing.T) {
+	var cc CacheControl
+	if err := cc.Parse("Max-Age=\"10\" ,no-cache=\"set-cookie, x-foo\",, x-ext=1"); err != nil {
+		t.Fatal(err)
+	}
+	if cc.MaxAge == nil || *cc.MaxAge != 10*time.Second {
+		t.Errorf("expected max-age of 10s, got %v", cc.MaxAge)
+	}
+	if !cc.NoCache || len(cc.NoCacheFields) != 2 || cc.NoCacheFields[1] != "X-Foo" {
+		t.Errorf("unexpected no-cache fields %v", cc.NoCacheFields)
+	}
+	if err := cc.Parse("max-age=99999999999999"); err != nil {
+		t.Fatal(err)
+	}
+	if *cc.MaxAge != maxDeltaSeconds*time.Second {
+		t.Errorf("expected max-age to be capped, got %s", cc.MaxAge)
+	}
+}
+
+func TestCacheControlInvalid(t *testing.T) {
+	for _, hdr := range []string{
+		"public, private",
+		"max-age=1, max-age=2",
+		"no-store, NO-STORE",
+		"max-age=-1",
+		"max-age=1.5",
+		"s-maxage",
+		"private=\"Set-Cookie",
+	} {
+		t.Run(hdr, func(t *testing.T) {
+			var cc CacheControl
+			if err := cc.Parse(hdr); err == nil {
+				t.Errorf("expected err")
+			}
+		})
+	}
+}
+
+func TestRequestCacheControl(t *testing.T) {
+	verify(t, []testcase{
+		{&RequestCacheControl{}, ""},
+		{&RequestCacheControl{NoCache: true}, "no-cache"},
+		{&RequestCacheControl{NoStore: true, NoTransform: true, OnlyIfCached: true},
+			"no-store, no-transform, only-if-cached"},
+		{&RequestCacheControl{MaxAge: seconds(0)}, "max-age=0"},
+		{&RequestCacheControl{MaxStale: true}, "max-stale"},
+		{&RequestCacheControl{MaxStale: true, MaxStaleLimit: seconds(60), MinFresh: seconds(5)},
+			"max-stale=60, min-fresh=5"},
+	})
+}
+
+func TestRequestCacheControlAccepts(t *testing.T) {
+	for _, c := range []struct {
+		Request  string
+		Stored   CacheControl
+		Age      time.Duration
+		Lifetime time.Duration
+		Expected bool
+	}{
+		{"", CacheControl{}, 10 * time.Second, time.Minute, true},
+		{"", CacheControl{}, 2 * time.Minute, time.Minute, false},
+		{"", CacheControl{NoCache: true}, 0, time.Minute, false},
+		{"", CacheControl{NoCache: true, NoCacheFields: []string{"Set-Cookie"}}, 0, time.Minute, true},
+		{"no-cache", CacheControl{}, 0, time.Minute, false},
+		{"max-age=5", CacheControl{}, 10 * time.Second, time.Minute, false},
+		{"max-age=5", CacheControl{}, 5 * time.Second, time.Minute, true},
+		{"min-fresh=30", CacheControl{}, 40 * time.Second, time.Minute, false},
+		{"min-fresh=30", CacheControl{}, 20 * time.Second, time.Minute, true},
+		{"max-stale", CacheControl{}, time.Hour, time.Minute, true},
+		{"max-stale", CacheControl{MustRevalidate: true}, time.Hour, time.Minute, false},
+		{"max-stale=60", CacheControl{}, 90 * time.Second, time.Minute, true},
+		{"max-stale=60", CacheControl{}, 3 * time.Minute, time.Minute, false},
+	} {
+		var req RequestCacheControl
+		if err := req.Parse(c.Request); err != nil {
+			t.Fatal(err)
+		}
+		if actual := req.Accepts(c.Stored, c.Age, c.Lifetime); actual != c.Expected {
+			t.Errorf("%q with %q, age %s, lifetime %s: expected %v", c.Request, c.Stored.Value(), c.Age, c.Lifetime, c.Expected)
+		}
+	}
+}
+
+func TestRequestCacheControlAcceptsShared(t *testing.T) {
+	sixty := 60 * time.Second
+	for _, c := range []struct {
+		Stored   CacheControl
+		Age      time.Duration
+		Expected bool
+	}{
+		{CacheControl{}, time.Hour, true},
+		{CacheControl{SMaxAge: &sixty}, 30 * time.Second, true},
+		{CacheControl{SMaxAge: &sixty}, time.Hour, false},
+		{CacheControl{ProxyRevalidate: true}, time.Hour, false},
+		{CacheControl{MustRevalidate: true}, time.Hour, false},
+	} {
+		req := RequestCacheControl{MaxStale: true}
+		if actual := req.AcceptsShared(c.Stored, c.Age, time.Minute); actual != c.Expected {
+			t.Errorf("max-stale with %q, age %s: expected %v", c.Stored.Value(), c.Age, c.Expected)
+		}
+	}
+}
+
+func TestExpiresAndPragma(t *testing.T) {
+	verify(t, []testcase{
+		{&Expires{}, "0"},
+		{&Expires{time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)}, "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{&Pragma{}, ""},
+		{&Pragma{NoCache: true}, "no-cache"},
+	})
+
+	for _, hdr := range []string{"-1", "tomorrow", ""} {
+		expires := Expires{time.Now()}
+		if err := expires.Parse(hdr); err != nil || !expires.Time.IsZero() {
+			t.Errorf("%q: expected an expired date, got %s", hdr, expires.Time)
+		}
+	}
+	var pragma Pragma
+	if pragma.Parse("foo, No-Cache"); !pragma.NoCache {
+		t.Errorf("expected no-cache")
+	}
+}
+
+func TestRequestCacheControlPragma(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Pragma", "no-cache")
+	if !requestCacheControl(r).NoCache {
+		t.Errorf("expected Pragma to be honored without Cache-Control")
+	}
+	r.Header.Set("Cache-Control", "max-age=60")
+	if requestCacheControl(r).NoCache {
+		t.Errorf("expected Cache-Control to take precedence over Pragma")
+	}
+}
+
+func TestNeverCache(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	NeverCache(w)
+	for name, expected := range map[string]string{
+		"Cache-Control": "no-cache, no-store, must-revalidate, max-age=0",
+		"Expires":       "0",
+		"Pragma":        "no-cache",
+	} {
+		if v := w.Header().Values(name); len(v) != 1 || v[0] != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, v)
+		}
+	}
+}
+
+func TestCacheFor(t *testing.T) {
+	w := httptest.NewRecorder()
+	NeverCache(w)
+	CacheFor(w, time.Hour)
+	if v := w.Header().Get("Cache-Control"); v != "max-age=3600" {
+		t.Errorf("unexpected Cache-Control %q", v)
+	}
+	var expires Expires
+	expires.Parse(w.Header().Get("Expires"))
+	if d := time.Until(expires.Time); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("unexpected Expires %q", w.Header().Get("Expires"))
+	}
+	if _, ok := w.Header()["Pragma"]; ok {
+		t.Errorf("expected Pragma to be removed")
+	}
+}
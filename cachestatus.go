@@ -0,0 +1,192 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CacheFwd is the reason a cache forwarded a request towards the origin
+// server.
+type CacheFwd string
+
+const (
+	// The cache was configured to not handle this request.
+	CacheFwdBypass CacheFwd = "bypass"
+	// The request method's semantics require the request to be forwarded.
+	CacheFwdMethod CacheFwd = "method"
+	// The cache did not contain any responses that matched the request URI.
+	CacheFwdURIMiss CacheFwd = "uri-miss"
+	// The cache contained a response that matched the request URI, but it
+	// could not select a response based upon this request's header fields
+	// and stored Vary header fields.
+	CacheFwdVaryMiss CacheFwd = "vary-miss"
+	// The cache did not contain any responses that could be used to satisfy
+	// this request.
+	CacheFwdMiss CacheFwd = "miss"
+	// The cache was able to select a fresh response for the request, but the
+	// request's semantics (e.g., Cache-Control request directives) did not
+	// allow its use.
+	CacheFwdRequest CacheFwd = "request"
+	// The cache was able to select a response for the request, but it was
+	// stale.
+	CacheFwdStale CacheFwd = "stale"
+	// The cache was able to select a partial response for the request, but
+	// it did not contain all of the requested ranges.
+	CacheFwdPartial CacheFwd = "partial"
+)
+
+// CacheStatusEntry describes how a single cache handled a request.
+type CacheStatusEntry struct {
+	// Identifies the cache, such as a hostname or product name.
+	Cache string
+	// The request was satisfied by the cache.
+	Hit bool
+	// Why the request went forward, if it did.
+	Fwd CacheFwd
+	// The status code the next hop server returned in response to the
+	// forwarded request, or zero.
+	FwdStatus int
+	// The response's remaining freshness lifetime. A negative value means
+	// the response was served stale.
+	TTL *time.Duration
+	// The cache stored the response.
+	Stored bool
+	// The request was collapsed with another request.
+	Collapsed bool
+	// An implementation-specific representation of the stored response's
+	// cache key.
+	Key string
+	// Implementation-specific details.
+	Detail string
+}
+
+// The Cache-Status HTTP response header indicates how caches have handled a
+// request. Each cache that handles the request appends its own entry, so the
+// last entry belongs to the cache closest to the user agent.
+//
+// https://www.rfc-editor.org/rfc/rfc9211
+type CacheStatus struct {
+	Entries []CacheStatusEntry
+}
+
+func (h CacheStatus) Name() string {
+	return "Cache-Status"
+}
+
+func (h CacheStatus) Value() string {
+	items := make([]sfItem, len(h.Entries))
+	for i, e := range h.Entries {
+		it := sfItem{value: sfToken(e.Cache)}
+		if !isSFToken(e.Cache) {
+			it.value = e.Cache
+		}
+		if e.Hit {
+			it.params = append(it.params, sfParam{"hit", true})
+		}
+		if e.Fwd != "" {
+			it.params = append(it.params, sfParam{"fwd", sfToken(e.Fwd)})
+		}
+		if e.FwdStatus != 0 {
+			it.params = append(it.params, sfParam{"fwd-status", int64(e.FwdStatus)})
+		}
+		if e.TTL != nil {
+			it.params = append(it.params, sfParam{"ttl", int64(*e.TTL / time.Second)})
+		}
+		if e.Stored {
+			it.params = append(it.params, sfParam{"stored", true})
+		}
+		if e.Collapsed {
+			it.params = append(it.params, sfParam{"collapsed", true})
+		}
+		if e.Key != "" {
+			it.params = append(it.params, sfParam{"key", e.Key})
+		}
+		if e.Detail != "" {
+			it.params = append(it.params, sfParam{"detail", e.Detail})
+		}
+		items[i] = it
+	}
+	return formatSFList(items)
+}
+
+func (h *CacheStatus) Parse(hdr string) error {
+	items, err := parseSFList(hdr)
+	if err != nil {
+		return fmt.Errorf("Invalid Cache-Status: %s", err)
+	}
+	val := CacheStatus{}
+	for _, it := range items {
+		var e CacheStatusEntry
+		switch v := it.value.(type) {
+		case sfToken:
+			e.Cache = string(v)
+		case string:
+			e.Cache = v
+		default:
+			return fmt.Errorf("Cache-Status cache names must be tokens or strings; got %s", hdr)
+		}
+		for _, p := range it.params {
+			switch v := p.value.(type) {
+			case bool:
+				switch p.key {
+				case "hit":
+					e.Hit = v
+				case "stored":
+					e.Stored = v
+				case "collapsed":
+					e.Collapsed = v
+				}
+			case sfToken:
+				if p.key == "fwd" {
+					e.Fwd = CacheFwd(v)
+				} else if p.key == "detail" {
+					e.Detail = string(v)
+				}
+			case int64:
+				if p.key == "fwd-status" {
+					e.FwdStatus = int(v)
+				} else if p.key == "ttl" {
+					ttl := time.Duration(v) * time.Second
+					e.TTL = &ttl
+				}
+			case string:
+				if p.key == "key" {
+					e.Key = v
+				} else if p.key == "detail" {
+					e.Detail = v
+				}
+			}
+		}
+		val.Entries = append(val.Entries, e)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &CacheStatus{}
+
+// AppendCacheStatus adds an entry to the end of the Cache-Status header of a
+// response. The entries added by other caches are kept as they were sent,
+// including any this package can't parse.
+func AppendCacheStatus(h http.Header, e CacheStatusEntry) {
+	cs := CacheStatus{Entries: []CacheStatusEntry{e}}
+	v := cs.Value()
+	if existing := strings.Join(h.Values(cs.Name()), ", "); strings.TrimSpace(existing) != "" {
+		v = existing + ", " + v
+	}
+	h.Set(cs.Name(), v)
+}
+
+func isSFToken(s string) bool {
+	if s == "" || !(s[0] == '*' || s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
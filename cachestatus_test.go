@@ -0,0 +1,103 @@
+package headers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheStatus(t *testing.T) {
+	verify(t, []testcase{
+		{&CacheStatus{}, ""},
+		{&CacheStatus{[]CacheStatusEntry{{Cache: "ExampleCache", Hit: true, TTL: seconds(376)}}},
+			"ExampleCache;hit;ttl=376"},
+		{&CacheStatus{[]CacheStatusEntry{{Cache: "ExampleCache", Fwd: CacheFwdStale, FwdStatus: 304, Stored: true, TTL: seconds(-412)}}},
+			"ExampleCache;fwd=stale;fwd-status=304;ttl=-412;stored"},
+		{&CacheStatus{[]CacheStatusEntry{
+			{Cache: "OriginCache", Hit: true, TTL: seconds(1100)},
+			{Cache: "CDN Company Here", Fwd: CacheFwdURIMiss, Collapsed: true, Key: "/a \"b\"", Detail: "cluster-3"},
+		}}, "OriginCache;hit;ttl=1100, \"CDN Company Here\";fwd=uri-miss;collapsed;key=\"/a \\\"b\\\"\";detail=\"cluster-3\""},
+	})
+}
+
+func TestCacheStatusParse(t *testing.T) {
+	var cs CacheStatus
+	if err := cs.Parse(`ReverseProxyCache; hit=?1, ForwardProxyCache; fwd=uri-miss; collapsed=?0; stored;detail=memory, "BrowserCache";fwd=vary-miss;x-ext=1.5`); err == nil {
+		t.Fatalf("expected decimals to be rejected")
+	}
+	if err := cs.Parse(`ReverseProxyCache; hit=?1, ForwardProxyCache; fwd=uri-miss; collapsed=?0; stored;detail=memory, "BrowserCache";fwd=vary-miss;x-ext=15`); err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(cs.Entries))
+	}
+	if e := cs.Entries[0]; e.Cache != "ReverseProxyCache" || !e.Hit {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := cs.Entries[1]; e.Fwd != CacheFwdURIMiss || e.Collapsed || !e.Stored || e.Detail != "memory" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if e := cs.Entries[2]; e.Cache != "BrowserCache" || e.Fwd != CacheFwdVaryMiss {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	for _, hdr := range []string{
+		"a,",
+		"a;hit=?2",
+		"a;fwd=\"unterminated",
+		"a;Hit",
+		"(a b)",
+	} {
+		if err := cs.Parse(hdr); err == nil {
+			t.Errorf("%s: expected err", hdr)
+		}
+	}
+}
+
+func TestAppendCacheStatus(t *testing.T) {
+	h := http.Header{}
+	h.Add("Cache-Status", "Origin;hit")
+	AppendCacheStatus(h, CacheStatusEntry{Cache: "edge", Fwd: CacheFwdMiss, FwdStatus: 200})
+	if v := h.Values("Cache-Status"); len(v) != 1 || v[0] != "Origin;hit, edge;fwd=miss;fwd-status=200" {
+		t.Errorf("unexpected Cache-Status %q", v)
+	}
+
+	// Entries from other layers are kept verbatim, even those using
+	// parameters or values this package doesn't parse.
+	for _, existing := range []string{"upstream;hit;ttl=30;x-score=1.5", "upstream;hit;x-ext=foo", `upstream;detail=:AQI=:, mid;fwd=miss`} {
+		h := http.Header{"Cache-Status": {existing}}
+		AppendCacheStatus(h, CacheStatusEntry{Cache: "edge", Hit: true})
+		if v := h.Get("Cache-Status"); v != existing+", edge;hit" {
+			t.Errorf("%q: unexpected Cache-Status %q", existing, v)
+		}
+	}
+}
+
+func TestSharedCacheStatus(t *testing.T) {
+	c, clock := newTestSharedCache()
+	c.Name = "edge"
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		w.Header().Set("Vary", "Accept-Language")
+	}))
+
+	for _, c := range []struct {
+		Method   string
+		Headers  map[string]string
+		Elapsed  time.Duration
+		Expected string
+	}{
+		{"GET", nil, 0, "edge;fwd=uri-miss;fwd-status=200;ttl=60;stored"},
+		{"GET", nil, 10 * time.Second, "edge;hit;ttl=50"},
+		{"GET", map[string]string{"Accept-Language": "fr"}, 0, "edge;fwd=vary-miss;fwd-status=200;ttl=60;stored"},
+		{"GET", map[string]string{"Accept-Language": "fr", "Cache-Control": "no-cache"}, 0, "edge;fwd=request;fwd-status=200;ttl=60;stored"},
+		{"GET", map[string]string{"Accept-Language": "fr"}, 2 * time.Minute, "edge;fwd=stale;fwd-status=200;ttl=60;stored"},
+		{"POST", nil, 0, "edge;fwd=method;fwd-status=200"},
+	} {
+		clock.Advance(c.Elapsed)
+		w := serve(h, c.Method, "/", c.Headers)
+		if actual := w.Header().Get("Cache-Status"); actual != c.Expected {
+			t.Errorf("%s %v: expected %q, got %q", c.Method, c.Headers, c.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package headers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Charset is a member of an Accept-Charset header: a character encoding,
+// such as "utf-8", or "*", and a weight.
+type Charset struct {
+	Name string
+	// The relative preference for the charset, between 0 and 1. A weight of 0
+	// means "not acceptable".
+	Q float64
+}
+
+func (c Charset) String() string {
+	return formatWeighted(c.Name, nil, c.Q)
+}
+
+// The Accept-Charset request HTTP header advertises which character
+// encodings the client understands. Using content negotiation, the server
+// selects one of the encodings, uses it, and informs the client of its choice
+// within the Content-Type response header, usually in a charset= parameter.
+//
+// https://mdn.io/Accept-Charset
+type AcceptCharset struct {
+	Charsets []Charset
+}
+
+func (h AcceptCharset) Name() string {
+	return "Accept-Charset"
+}
+
+func (h AcceptCharset) Value() string {
+	v := make([]string, len(h.Charsets))
+	for i, c := range h.Charsets {
+		v[i] = c.String()
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *AcceptCharset) Parse(hdr string) error {
+	list, err := parseWeightedList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	val := AcceptCharset{}
+	for _, w := range list {
+		if !isToken(w.value) {
+			return fmt.Errorf("Invalid charset in Accept-Charset: %s", w.value)
+		}
+		val.Charsets = append(val.Charsets, Charset{Name: w.value, Q: w.q})
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &AcceptCharset{}
+
+// Quality returns the weight the header gives to a charset. Charsets are
+// compared case-insensitively, and those not listed take the weight of "*",
+// if present.
+func (h AcceptCharset) Quality(charset string) float64 {
+	wildcard := 0.0
+	for _, c := range h.Charsets {
+		if strings.EqualFold(c.Name, charset) {
+			return c.Q
+		}
+		if c.Name == "*" {
+			wildcard = c.Q
+		}
+	}
+	return wildcard
+}
@@ -0,0 +1,52 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptCharset(t *testing.T) {
+	verify(t, []testcase{
+		{&AcceptCharset{Charsets: []Charset{{Name: "utf-8", Q: 1}}}, "utf-8"},
+		{&AcceptCharset{Charsets: []Charset{{Name: "iso-8859-1", Q: 1}, {Name: "utf-8", Q: 0.7}, {Name: "*", Q: 0.1}}}, "iso-8859-1, utf-8;q=0.7, *;q=0.1"},
+	})
+
+	var h AcceptCharset
+	if err := h.Parse("utf-8;q=1.1"); err == nil {
+		t.Errorf("expected error for invalid weight")
+	}
+	h.Parse("ISO-8859-1, utf-8;q=0.5, *;q=0.1")
+	for charset, q := range map[string]float64{"iso-8859-1": 1, "UTF-8": 0.5, "shift_jis": 0.1} {
+		if got := h.Quality(charset); got != q {
+			t.Errorf("expected %s to have quality %v, got %v", charset, q, got)
+		}
+	}
+	if q := (AcceptCharset{}).Quality("utf-8"); q != 0 {
+		t.Errorf("expected empty Accept-Charset to accept nothing, got %v", q)
+	}
+}
+
+func TestNegotiatorCharset(t *testing.T) {
+	n := Negotiator{ContentTypes: []string{"text/html", "text/plain;format=flowed"}, Charsets: []string{"utf-8", "iso-8859-1"}}
+	for _, c := range []struct {
+		accept, charset, selected, expected string
+		status                              int
+	}{
+		{"", "", "utf-8", "text/html; charset=utf-8", http.StatusOK},
+		{"text/plain", "iso-8859-1", "iso-8859-1", "text/plain; charset=iso-8859-1; format=flowed", http.StatusOK},
+		{"", "ISO-8859-1;q=0.5, utf-8", "utf-8", "text/html; charset=utf-8", http.StatusOK},
+		{"", "shift_jis", "", "text/html", http.StatusNotAcceptable},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", c.accept)
+		r.Header.Set("Accept-Charset", c.charset)
+		result, status := n.Negotiate(r)
+		if result.Charset != c.selected || result.MediaType() != c.expected || status != c.status {
+			t.Errorf("%q: unexpected result %+v %q %d", c.charset, result, result.MediaType(), status)
+		}
+		if len(result.Vary) != 2 || result.Vary[1] != "Accept-Charset" {
+			t.Errorf("unexpected Vary %v", result.Vary)
+		}
+	}
+}
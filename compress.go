@@ -0,0 +1,306 @@
+package headers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Compress is an http.Handler middleware which compresses response bodies
+// with the content coding negotiated from the request's Accept-Encoding.
+//
+// Responses smaller than MinSize, responses which already have a
+// Content-Encoding or Content-Range, and responses whose Content-Type is
+// already compressed, such as images, are sent as is. Every other response
+// gets "Vary: Accept-Encoding", whether or not it was compressed.
+//
+// A compressed response is a different representation from the original, so
+// a strong ETag is given the coding as a suffix, as in "abc;gzip", or made
+// weak if WeakETags is set. The suffix is removed from the If-None-Match and
+// If-Match headers of requests before they reach the wrapped handler, and
+// restored on a 304 (Not Modified) response to such a request. Weak ETags
+// already allow for the difference and are left alone.
+type Compress struct {
+	// The content codings to offer, in order of preference. Supported codings
+	// are "gzip" and "deflate". If nil, both are offered, gzip first.
+	Encodings []string
+	// The compression level, as defined by compress/flate. If zero,
+	// DefaultCompression is used.
+	Level int
+	// The smallest response body, in bytes, worth compressing. If zero,
+	// DefaultCompressMinSize is used.
+	MinSize int
+	// Make the ETags of compressed responses weak, rather than suffixing them.
+	WeakETags bool
+}
+
+// The smallest response body Compress compresses by default.
+const DefaultCompressMinSize = 1024
+
+// Handler wraps next with the middleware.
+func (c Compress) Handler(next http.Handler) http.Handler {
+	if c.Encodings == nil {
+		c.Encodings = []string{"gzip", "deflate"}
+	}
+	if c.Level == 0 {
+		c.Level = gzip.DefaultCompression
+	}
+	if c.MinSize == 0 {
+		c.MinSize = DefaultCompressMinSize
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		coding, _ := NegotiateEncoding(r, c.Encodings...)
+		r, validated := c.stripETagSuffixes(r, coding)
+		if r.Method == http.MethodHead {
+			// Without a body there is nothing to compress, but the response
+			// still depends on the request's Accept-Encoding.
+			coding = "identity"
+		}
+		cw := &compressWriter{ResponseWriter: w, c: c, coding: coding, validated: validated, status: http.StatusOK}
+		next.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+// stripETagSuffixes removes the suffix of the negotiated coding, as added to
+// ETags by the middleware, from the request's conditional headers, so that
+// the wrapped handler can compare them with its own ETags. It also returns
+// the tags of the validators which stood for compressed responses, mapped to
+// their coding.
+func (c Compress) stripETagSuffixes(r *http.Request, coding string) (*http.Request, map[string]string) {
+	var clone *http.Request
+	validated := map[string]string{}
+	for _, name := range []string{IfNoneMatch{}.Name(), IfMatch{}.Name()} {
+		hdr := r.Header.Get(name)
+		wildcard, tags, err := parseETagList(name, hdr)
+		if hdr == "" || wildcard || err != nil {
+			continue
+		}
+		for i := range tags {
+			if tags[i].Weak {
+				if c.WeakETags {
+					validated[tags[i].Tag] = ""
+				}
+				continue
+			}
+			if c.WeakETags || coding != "gzip" && coding != "deflate" {
+				continue
+			}
+			if stripped := strings.TrimSuffix(tags[i].Tag, etagSuffix(coding)); stripped != tags[i].Tag {
+				tags[i].Tag = stripped
+				validated[stripped] = coding
+			}
+		}
+		if stripped := formatETagList(false, tags); stripped != hdr {
+			if clone == nil {
+				clone = r.Clone(r.Context())
+			}
+			clone.Header.Set(name, stripped)
+		}
+	}
+	if clone == nil {
+		return r, validated
+	}
+	return clone, validated
+}
+
+// compressedTypes lists media types which are already compressed, in
+// addition to image, audio and video types.
+var compressedTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/zip":              true,
+	"application/zstd":             true,
+	"application/x-bzip2":          true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/x-xz":             true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+}
+
+// compressible reports whether a response with the given media type is
+// worth compressing.
+func compressible(contentType string) bool {
+	mediaType := contentType
+	if semi := strings.IndexByte(mediaType, ';'); semi >= 0 {
+		mediaType = mediaType[:semi]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return !compressedTypes[mediaType]
+}
+
+// compressWriter buffers the start of a response until it has enough of the
+// body to decide whether to compress it, then writes through the encoder,
+// if any.
+type compressWriter struct {
+	http.ResponseWriter
+	c           Compress
+	coding      string
+	validated   map[string]string
+	buf         bytes.Buffer
+	enc         io.WriteCloser
+	status      int
+	wroteHeader bool
+	decided     bool
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if status < 200 {
+		// Informational responses pass straight through.
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+	if !bodyAllowed(status) {
+		w.decide(true)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() < w.c.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far to the client, flushing the
+// encoder first if the response is compressed. A response flushed before
+// MinSize bytes are written is compressed, as more of it is on the way.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+		if !w.decided {
+			w.decide(false)
+		}
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// decide settles the response headers and flushes the buffered body. The
+// whole body has been buffered if final is set.
+func (w *compressWriter) decide(final bool) error {
+	w.decided = true
+	h := w.Header()
+	if _, ok := h["Content-Type"]; !ok && w.buf.Len() > 0 {
+		// Sniff the type of the original body, as net/http would.
+		h.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	if h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" && compressible(h.Get("Content-Type")) {
+		switch {
+		case !bodyAllowed(w.status):
+			// A 304 (Not Modified) for a validator the middleware produced
+			// stands for a compressed response the client stored, so its
+			// ETag must match that response's.
+			var etag ETag
+			if etag.Parse(h.Get(etag.Name())) != nil || etag.Weak {
+				break
+			}
+			if coding, ok := w.validated[etag.Tag]; ok {
+				AddVary(w.ResponseWriter, AcceptEncoding{}.Name())
+				w.adjustETag(coding)
+			}
+		default:
+			AddVary(w.ResponseWriter, AcceptEncoding{}.Name())
+			if w.coding != "identity" && w.status != http.StatusPartialContent && (!final || w.buf.Len() >= w.c.MinSize) {
+				w.encode()
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// encode sets up the encoder for the negotiated coding, and updates the
+// response headers to match.
+func (w *compressWriter) encode() {
+	switch w.coding {
+	case "gzip":
+		w.enc, _ = gzip.NewWriterLevel(w.ResponseWriter, w.c.Level)
+	case "deflate":
+		w.enc, _ = zlib.NewWriterLevel(w.ResponseWriter, w.c.Level)
+	}
+	if w.enc == nil {
+		return
+	}
+	h := w.Header()
+	h.Set(ContentEncoding{}.Name(), w.coding)
+	h.Del("Content-Length")
+	w.adjustETag(w.coding)
+}
+
+// adjustETag gives a strong ETag the suffix of a coding, or makes it weak if
+// the middleware is configured to.
+func (w *compressWriter) adjustETag(coding string) {
+	h := w.Header()
+	var etag ETag
+	if hdr := h.Get(etag.Name()); hdr != "" && etag.Parse(hdr) == nil && !etag.Weak {
+		if w.c.WeakETags {
+			etag.Weak = true
+		} else {
+			etag.Tag += etagSuffix(coding)
+		}
+		h.Set(etag.Name(), etag.Value())
+	}
+}
+
+// etagSuffix returns the suffix given to strong ETags of responses compressed
+// with a coding. The separator is one handlers are unlikely to use in their
+// own ETags, so that only suffixes added by the middleware are removed.
+func etagSuffix(coding string) string {
+	return ";" + coding
+}
+
+// close finishes the response once the wrapped handler has returned.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+		if !w.decided {
+			w.decide(true)
+		}
+	}
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
@@ -0,0 +1,215 @@
+package headers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("hello, world\n", 200)
+	h := Compress{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte("hello"))
+			return
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "2600")
+		w.Write([]byte(body[:100]))
+		w.Write([]byte(body[100:]))
+	}))
+
+	decode := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		},
+	}
+	for accept, coding := range map[string]string{"gzip, deflate": "gzip", "deflate": "deflate", "br, deflate;q=0.5, identity;q=0.1": "deflate"} {
+		w := serve(h, "GET", "/", map[string]string{"Accept-Encoding": accept})
+		if w.Header().Get("Content-Encoding") != coding || w.Header().Get("Content-Length") != "" {
+			t.Fatalf("%q: unexpected headers %v", accept, w.Header())
+		}
+		if w.Header().Get("ETag") != `"v1;`+coding+`"` || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: unexpected headers %v", accept, w.Header())
+		}
+		if w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Errorf("expected the type of the original body, got %q", w.Header().Get("Content-Type"))
+		}
+		r, err := decode[coding](w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := ioutil.ReadAll(r); string(b) != body {
+			t.Errorf("%q: unexpected body %q", accept, b)
+		}
+	}
+
+	for _, c := range []struct {
+		path, accept, vary string
+	}{
+		{"/", "", "Accept-Encoding"},
+		{"/", "br", "Accept-Encoding"},
+		{"/small", "gzip", "Accept-Encoding"},
+		{"/png", "gzip", ""},
+		{"/encoded", "gzip", ""},
+	} {
+		w := serve(h, "GET", c.path, map[string]string{"Accept-Encoding": c.accept})
+		if w.Header().Get("Vary") != c.vary || w.Header().Get("Content-Encoding") == "gzip" {
+			t.Errorf("%s %q: unexpected headers %v", c.path, c.accept, w.Header())
+		}
+		if c.path == "/small" && w.Body.String() != "hello" || c.path == "/" && w.Body.String() != body {
+			t.Errorf("%s %q: unexpected body %q", c.path, c.accept, w.Body.String())
+		}
+	}
+
+	w := serve(h, "GET", "/empty", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+	w = serve(h, "HEAD", "/", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("unexpected HEAD headers %v", w.Header())
+	}
+}
+
+func TestCompressETags(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("If-None-Match")
+		w.Header().Set("ETag", r.URL.Query().Get("etag"))
+		w.Write([]byte(strings.Repeat("a", 2000)))
+	})
+
+	w := serve(Compress{}.Handler(next), "GET", `/?etag="v1"`, map[string]string{
+		"Accept-Encoding": "gzip",
+		"If-None-Match":   `"v1;gzip", "v2;deflate", "build-gzip", W/"v3;gzip"`,
+	})
+	if seen != `"v1", "v2;deflate", "build-gzip", W/"v3;gzip"` {
+		t.Errorf("unexpected If-None-Match %q", seen)
+	}
+	if w.Header().Get("ETag") != `"v1;gzip"` {
+		t.Errorf("unexpected ETag %q", w.Header().Get("ETag"))
+	}
+
+	w = serve(Compress{}.Handler(next), "GET", `/?etag=W/"v1"`, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("ETag") != `W/"v1"` {
+		t.Errorf("expected weak ETag to be left alone, got %q", w.Header().Get("ETag"))
+	}
+	w = serve(Compress{WeakETags: true}.Handler(next), "GET", `/?etag="v1"`, map[string]string{"Accept-Encoding": "gzip"})
+	if w.Header().Get("ETag") != `W/"v1"` {
+		t.Errorf("expected weakened ETag, got %q", w.Header().Get("ETag"))
+	}
+}
+
+func TestCompressNotModified(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		if inm := r.Header.Get("If-None-Match"); inm == `"abc"` || inm == `W/"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(strings.Repeat("a", 2000)))
+	})
+	h := Compress{}.Handler(next)
+
+	w := serve(h, "GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+	etag := w.Header().Get("ETag")
+	if etag != `"abc;gzip"` {
+		t.Fatalf("unexpected ETag %q", etag)
+	}
+	w = serve(h, "GET", "/", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != etag || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected 304 with ETag %s, got %d %v", etag, w.Code, w.Header())
+	}
+	w = serve(h, "GET", "/", map[string]string{"If-None-Match": `"abc"`})
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"abc"` {
+		t.Errorf("expected 304 with the identity ETag, got %d %v", w.Code, w.Header())
+	}
+	w = serve(Compress{WeakETags: true}.Handler(next), "GET", "/", map[string]string{"Accept-Encoding": "deflate", "If-None-Match": `W/"abc"`})
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf("expected 304 with a weak ETag, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCompressNotModifiedUncompressed(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		size        int
+	}{
+		{"image", "image/png", 2000},
+		{"small body", "text/plain", 10},
+	} {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"img1"`)
+			if r.Header.Get("If-None-Match") == `"img1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", tc.contentType)
+			w.Write([]byte(strings.Repeat("a", tc.size)))
+		})
+		h := Compress{}.Handler(next)
+
+		w := serve(h, "GET", "/", map[string]string{"Accept-Encoding": "gzip"})
+		if w.Header().Get("Content-Encoding") != "" || w.Header().Get("ETag") != `"img1"` {
+			t.Fatalf("%s: expected an uncompressed response, got %v", tc.name, w.Header())
+		}
+		w = serve(h, "GET", "/", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": `"img1"`})
+		if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"img1"` || w.Header().Get("Vary") != "" {
+			t.Errorf("%s: expected 304 matching the stored response, got %d %v", tc.name, w.Code, w.Header())
+		}
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var flushed string
+	h := Compress{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		zr, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 9)
+		if _, err := io.ReadFull(zr, b); err != nil {
+			t.Fatal(err)
+		}
+		flushed = string(b)
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, r)
+
+	if !rec.Flushed || flushed != "data: 1\n\n" {
+		t.Errorf("expected the first event to be flushed, got %q", flushed)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("unexpected headers %v", rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("unexpected body %q", b)
+	}
+}
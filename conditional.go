@@ -0,0 +1,334 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The ETag HTTP response header is an identifier for a specific version of a
+// resource. It lets caches be more efficient and save bandwidth, as a web
+// server does not need to resend a full response if the content has not
+// changed.
+//
+// https://mdn.io/ETag
+type ETag struct {
+	// The opaque tag, without the surrounding double quotes.
+	Tag string
+	// Weak validators are easy to generate but are far less useful for
+	// comparisons. Two weak tags may match even if the representations are
+	// not byte-for-byte identical.
+	Weak bool
+}
+
+func (h ETag) Name() string {
+	return "ETag"
+}
+
+func (h ETag) Value() string {
+	if h.Weak {
+		return "W/\"" + h.Tag + "\""
+	}
+	return "\"" + h.Tag + "\""
+}
+
+func (h *ETag) Parse(hdr string) error {
+	val := ETag{}
+	tag := strings.TrimSpace(hdr)
+	if strings.HasPrefix(tag, "W/") {
+		val.Weak = true
+		tag = tag[2:]
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return fmt.Errorf("An entity-tag must be a quoted string; got %s", hdr)
+	}
+	val.Tag = tag[1 : len(tag)-1]
+	if !validETag(val.Tag) {
+		return fmt.Errorf("Invalid character in entity-tag; got %s", hdr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ETag{}
+
+// StrongMatch reports whether both entity-tags are not weak and their opaque
+// tags are identical.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-8.8.3.2
+func (h ETag) StrongMatch(other ETag) bool {
+	return !h.Weak && !other.Weak && h.Tag == other.Tag
+}
+
+// WeakMatch reports whether the opaque tags of both entity-tags are
+// identical, regardless of either or both being tagged as weak.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-8.8.3.2
+func (h ETag) WeakMatch(other ETag) bool {
+	return h.Tag == other.Tag
+}
+
+// validETag reports whether tag only contains etagc characters: any visible
+// character except the double quote, or obs-text.
+func validETag(tag string) bool {
+	for i := 0; i < len(tag); i++ {
+		if c := tag[i]; c < 0x21 || c == '"' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// parseETagList parses the value of If-Match and If-None-Match, which is
+// either "*" or a comma-separated list of entity-tags.
+func parseETagList(name, hdr string) (bool, []ETag, error) {
+	if strings.TrimSpace(hdr) == "*" {
+		return true, nil, nil
+	}
+	var tags []ETag
+	for _, member := range splitETagList(hdr) {
+		var tag ETag
+		if err := tag.Parse(member); err != nil {
+			return false, nil, fmt.Errorf("Invalid entity-tag in %s: %s", name, err)
+		}
+		tags = append(tags, tag)
+	}
+	return false, tags, nil
+}
+
+// splitETagList splits a comma-separated list of entity-tags. Unlike
+// splitList, a backslash is an ordinary etagc character rather than an
+// escape, so only double quotes delimit the opaque tags.
+func splitETagList(input string) []string {
+	var members []string
+	quoted, start := false, 0
+	flush := func(end int) {
+		if m := strings.TrimSpace(input[start:end]); m != "" {
+			members = append(members, m)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(input); i++ {
+		switch {
+		case input[i] == '"':
+			quoted = !quoted
+		case input[i] == ',' && !quoted:
+			flush(i)
+		}
+	}
+	flush(len(input))
+	return members
+}
+
+func formatETagList(wildcard bool, tags []ETag) string {
+	if wildcard {
+		return "*"
+	}
+	v := make([]string, len(tags))
+	for i, tag := range tags {
+		v[i] = tag.Value()
+	}
+	return strings.Join(v, ", ")
+}
+
+// The If-Match HTTP request header makes the request conditional. For GET and
+// HEAD methods, the server will return the requested resource only if it
+// matches one of the listed ETags. For PUT and other non-safe methods, it
+// will only upload the resource in this case.
+//
+// https://mdn.io/If-Match
+type IfMatch struct {
+	// Matches any current representation of the resource.
+	Any  bool
+	Tags []ETag
+}
+
+func (h IfMatch) Name() string {
+	return "If-Match"
+}
+
+func (h IfMatch) Value() string {
+	return formatETagList(h.Any, h.Tags)
+}
+
+func (h *IfMatch) Parse(hdr string) error {
+	wildcard, tags, err := parseETagList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	*h = IfMatch{wildcard, tags}
+	return nil
+}
+
+var _ Header = &IfMatch{}
+
+// Matches reports whether the condition holds for the current representation,
+// using the strong comparison function. A nil current entity-tag means the
+// resource has no current representation.
+func (h IfMatch) Matches(current *ETag) bool {
+	if current == nil {
+		return false
+	}
+	if h.Any {
+		return true
+	}
+	for _, tag := range h.Tags {
+		if tag.StrongMatch(*current) {
+			return true
+		}
+	}
+	return false
+}
+
+// The If-None-Match HTTP request header makes the request conditional. For
+// GET and HEAD methods, the server will return the requested resource, with a
+// 200 status, only if it doesn't have an ETag matching the given ones. For
+// other methods, the request will be processed only if the eventually
+// existing resource's ETag doesn't match any of the values listed.
+//
+// https://mdn.io/If-None-Match
+type IfNoneMatch struct {
+	// Matches any current representation of the resource.
+	Any  bool
+	Tags []ETag
+}
+
+func (h IfNoneMatch) Name() string {
+	return "If-None-Match"
+}
+
+func (h IfNoneMatch) Value() string {
+	return formatETagList(h.Any, h.Tags)
+}
+
+func (h *IfNoneMatch) Parse(hdr string) error {
+	wildcard, tags, err := parseETagList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	*h = IfNoneMatch{wildcard, tags}
+	return nil
+}
+
+var _ Header = &IfNoneMatch{}
+
+// Matches reports whether any listed entity-tag matches the current
+// representation, using the weak comparison function. The condition of the
+// header holds when Matches returns false.
+func (h IfNoneMatch) Matches(current *ETag) bool {
+	if current == nil {
+		return false
+	}
+	if h.Any {
+		return true
+	}
+	for _, tag := range h.Tags {
+		if tag.WeakMatch(*current) {
+			return true
+		}
+	}
+	return false
+}
+
+// Precondition is the outcome of evaluating the conditional headers of a
+// request against the current state of the target resource.
+type Precondition int8
+
+const (
+	// The request should be processed normally.
+	PreconditionProceed Precondition = iota
+	// The server should respond with 304 (Not Modified).
+	PreconditionNotModified
+	// The server should respond with 412 (Precondition Failed).
+	PreconditionFailed
+	// The request should be processed normally, but the Range header must be
+	// ignored and the full representation sent.
+	PreconditionIgnoreRange
+)
+
+// EvaluatePreconditions evaluates the If-Match, If-Unmodified-Since,
+// If-None-Match, If-Modified-Since and If-Range headers of r in the order
+// defined by RFC 9110. The current representation is described by its
+// entity-tag, which is nil if it has none, and its last modification time,
+// which is zero if unknown.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2
+func EvaluatePreconditions(r *http.Request, etag *ETag, modified time.Time) Precondition {
+	return evaluatePreconditions(r, etag, modified, time.Now())
+}
+
+func evaluatePreconditions(r *http.Request, etag *ETag, modified time.Time, now time.Time) Precondition {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if hdr := r.Header.Get("If-Match"); hdr != "" {
+		var im IfMatch
+		if err := im.Parse(hdr); err != nil || !im.Matches(etag) {
+			return PreconditionFailed
+		}
+	} else if hdr := r.Header.Get("If-Unmodified-Since"); hdr != "" && !modified.IsZero() {
+		var ius IfUnmodifiedSince
+		if err := ius.Parse(hdr); err == nil && !ius.Unmodified(modified) {
+			return PreconditionFailed
+		}
+	}
+
+	if hdr := r.Header.Get("If-None-Match"); hdr != "" {
+		var inm IfNoneMatch
+		if err := inm.Parse(hdr); err == nil && inm.Matches(etag) {
+			if safe {
+				return PreconditionNotModified
+			}
+			return PreconditionFailed
+		}
+	} else if hdr := r.Header.Get("If-Modified-Since"); hdr != "" && safe && !modified.IsZero() {
+		// A date later than the server's current time is invalid and the
+		// header is ignored.
+		var ims IfModifiedSince
+		if err := ims.Parse(hdr); err == nil && !ims.Time.After(now) && !ims.Modified(modified) {
+			return PreconditionNotModified
+		}
+	}
+
+	if r.Method == http.MethodGet && r.Header.Get("Range") != "" {
+		if hdr := r.Header.Get("If-Range"); hdr != "" {
+			var ir IfRange
+			if err := ir.Parse(hdr); err != nil || !ir.Matches(etag, modified) {
+				return PreconditionIgnoreRange
+			}
+		}
+	}
+	return PreconditionProceed
+}
+
+// Conditional wraps a handler, answering conditional requests before they
+// reach it. The validators function returns the entity-tag and last
+// modification time of the representation selected by the request.
+//
+// Requests that fail their preconditions receive a 304 (Not Modified) or 412
+// (Precondition Failed) response. When If-Range does not match, the Range
+// header is removed before the request is passed on.
+func Conditional(validators func(r *http.Request) (*ETag, time.Time), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag, modified := validators(r)
+		switch EvaluatePreconditions(r, etag, modified) {
+		case PreconditionNotModified:
+			if etag != nil {
+				w.Header().Set(etag.Name(), etag.Value())
+			}
+			if !modified.IsZero() {
+				lm := LastModified{modified}
+				w.Header().Set(lm.Name(), lm.Value())
+			}
+			w.WriteHeader(http.StatusNotModified)
+		case PreconditionFailed:
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case PreconditionIgnoreRange:
+			r = r.Clone(r.Context())
+			r.Header.Del("Range")
+			next.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
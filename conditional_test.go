@@ -0,0 +1,181 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	verify(t, []testcase{
+		{&ETag{}, "\"\""},
+		{&ETag{Tag: "xyzzy"}, "\"xyzzy\""},
+		{&ETag{Tag: "xyzzy", Weak: true}, "W/\"xyzzy\""},
+		{&IfMatch{Any: true}, "*"},
+		{&IfMatch{Tags: []ETag{{Tag: "a"}, {Tag: "b,c", Weak: true}}}, "\"a\", W/\"b,c\""},
+		{&IfNoneMatch{Any: true}, "*"},
+		{&IfNoneMatch{Tags: []ETag{{Tag: "a"}}}, "\"a\""},
+		// Entity-tags have no escapes: a backslash is an ordinary character.
+		{&IfNoneMatch{Tags: []ETag{{Tag: `a\`}, {Tag: "b"}}}, `"a\", "b"`},
+	})
+}
+
+func TestETagInvalid(t *testing.T) {
+	for _, hdr := range []string{
+		"xyzzy",
+		"\"xyzzy",
+		"w/\"xyzzy\"",
+		"\"xy\"zzy\"",
+		"\"xy zzy\"",
+	} {
+		t.Run(hdr, func(t *testing.T) {
+			var tag ETag
+			if err := tag.Parse(hdr); err == nil {
+				t.Errorf("expected err")
+			}
+		})
+	}
+	var im IfMatch
+	if err := im.Parse("\"a\", b"); err == nil {
+		t.Errorf("expected err")
+	}
+}
+
+// The examples from RFC 9110, section 8.8.3.2.
+func TestETagComparison(t *testing.T) {
+	for _, c := range []struct {
+		A, B   ETag
+		Strong bool
+		Weak   bool
+	}{
+		{ETag{"1", true}, ETag{"1", true}, false, true},
+		{ETag{"1", true}, ETag{"2", true}, false, false},
+		{ETag{"1", true}, ETag{"1", false}, false, true},
+		{ETag{"1", false}, ETag{"1", false}, true, true},
+	} {
+		if c.A.StrongMatch(c.B) != c.Strong {
+			t.Errorf("%s, %s: expected strong match %v", c.A.Value(), c.B.Value(), c.Strong)
+		}
+		if c.A.WeakMatch(c.B) != c.Weak {
+			t.Errorf("%s, %s: expected weak match %v", c.A.Value(), c.B.Value(), c.Weak)
+		}
+	}
+}
+
+func TestETagListMatches(t *testing.T) {
+	var im IfMatch
+	var inm IfNoneMatch
+	if err := im.Parse("W/\"1\", \"2\""); err != nil {
+		t.Fatal(err)
+	}
+	if err := inm.Parse("W/\"1\", \"2\""); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		Current   *ETag
+		Match     bool
+		NoneMatch bool
+	}{
+		{nil, false, false},
+		{&ETag{"1", false}, false, true},
+		{&ETag{"2", false}, true, true},
+		{&ETag{"2", true}, false, true},
+		{&ETag{"3", false}, false, false},
+	} {
+		if im.Matches(c.Current) != c.Match {
+			t.Errorf("If-Match %v: expected %v", c.Current, c.Match)
+		}
+		if inm.Matches(c.Current) != c.NoneMatch {
+			t.Errorf("If-None-Match %v: expected %v", c.Current, c.NoneMatch)
+		}
+	}
+	if !(IfMatch{Any: true}).Matches(&ETag{Tag: "x"}) || (IfMatch{Any: true}).Matches(nil) {
+		t.Errorf("If-Match: * should match any current representation")
+	}
+}
+
+func TestEvaluatePreconditions(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	modified := now.Add(-time.Hour).Add(500 * time.Millisecond)
+	etag := &ETag{Tag: "v2"}
+	date := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	for i, c := range []struct {
+		Method   string
+		Headers  map[string]string
+		ETag     *ETag
+		Expected Precondition
+	}{
+		{"GET", nil, etag, PreconditionProceed},
+		{"GET", map[string]string{"If-Match": `"v2"`}, etag, PreconditionProceed},
+		{"GET", map[string]string{"If-Match": `W/"v2"`}, etag, PreconditionFailed},
+		{"PUT", map[string]string{"If-Match": `"v1"`}, etag, PreconditionFailed},
+		{"PUT", map[string]string{"If-Match": "*"}, nil, PreconditionFailed},
+		{"PUT", map[string]string{"If-Match": "*"}, etag, PreconditionProceed},
+		// If-Unmodified-Since is ignored when If-Match is present.
+		{"PUT", map[string]string{"If-Match": `"v2"`, "If-Unmodified-Since": date(now.Add(-2 * time.Hour))}, etag, PreconditionProceed},
+		{"PUT", map[string]string{"If-Unmodified-Since": date(now.Add(-2 * time.Hour))}, etag, PreconditionFailed},
+		{"PUT", map[string]string{"If-Unmodified-Since": date(modified)}, etag, PreconditionProceed},
+		{"PUT", map[string]string{"If-Unmodified-Since": "yesterday"}, etag, PreconditionProceed},
+		{"GET", map[string]string{"If-None-Match": `W/"v2"`}, etag, PreconditionNotModified},
+		{"HEAD", map[string]string{"If-None-Match": `"v1", "v2"`}, etag, PreconditionNotModified},
+		{"GET", map[string]string{"If-None-Match": `"v1"`}, etag, PreconditionProceed},
+		{"PUT", map[string]string{"If-None-Match": "*"}, etag, PreconditionFailed},
+		{"PUT", map[string]string{"If-None-Match": "*"}, nil, PreconditionProceed},
+		// If-Modified-Since is ignored when If-None-Match is present.
+		{"GET", map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": date(now)}, etag, PreconditionProceed},
+		{"GET", map[string]string{"If-Modified-Since": date(modified)}, etag, PreconditionNotModified},
+		{"GET", map[string]string{"If-Modified-Since": date(modified.Add(-time.Second))}, etag, PreconditionProceed},
+		{"GET", map[string]string{"If-Modified-Since": date(now.Add(time.Hour))}, etag, PreconditionProceed},
+		{"POST", map[string]string{"If-Modified-Since": date(now)}, etag, PreconditionProceed},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v2"`}, etag, PreconditionProceed},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`}, etag, PreconditionIgnoreRange},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": `W/"v2"`}, etag, PreconditionIgnoreRange},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": date(modified)}, etag, PreconditionProceed},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": date(now)}, etag, PreconditionIgnoreRange},
+		{"GET", map[string]string{"If-Range": `"v1"`}, etag, PreconditionProceed},
+	} {
+		r := httptest.NewRequest(c.Method, "/", nil)
+		for k, v := range c.Headers {
+			r.Header.Set(k, v)
+		}
+		if actual := evaluatePreconditions(r, c.ETag, modified, now); actual != c.Expected {
+			t.Errorf("%d: %s %v: expected %d, got %d", i, c.Method, c.Headers, c.Expected, actual)
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+	modified := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	handler := Conditional(func(r *http.Request) (*ETag, time.Time) {
+		return &ETag{Tag: "v2"}, modified
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Range")))
+	}))
+
+	for _, c := range []struct {
+		Method  string
+		Headers map[string]string
+		Status  int
+		Body    string
+	}{
+		{"GET", map[string]string{"If-None-Match": `"v2"`}, http.StatusNotModified, ""},
+		{"DELETE", map[string]string{"If-Match": `"v1"`}, http.StatusPreconditionFailed, ""},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`}, http.StatusOK, ""},
+		{"GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v2"`}, http.StatusOK, "bytes=0-1"},
+	} {
+		r := httptest.NewRequest(c.Method, "/", nil)
+		for k, v := range c.Headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.Status || w.Body.String() != c.Body {
+			t.Errorf("%s %v: expected %d %q, got %d %q", c.Method, c.Headers, c.Status, c.Body, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusNotModified && w.Header().Get("ETag") != `"v2"` {
+			t.Errorf("expected ETag on 304 response, got %q", w.Header().Get("ETag"))
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The Content-Type entity header is used to indicate the media type of the
+// resource.
+//
+// In responses, a Content-Type header tells the client what the content type
+// of the returned content actually is. Browsers will do MIME sniffing in some
+// cases and will not necessarily follow the value of this header; to prevent
+// this behavior, the header X-Content-Type-Options can be set to nosniff.
+//
+// Parse is as tolerant as browsers are, following the WHATWG "parse a MIME
+// type" algorithm: invalid parameters are skipped rather than rejected.
+// ParseStrict implements the RFC 9110 grammar instead, for APIs which should
+// reject malformed requests.
+//
+// https://mdn.io/Content-Type
+type ContentType struct {
+	// The top-level type, such as "application", in lower case.
+	Type string
+	// The subtype, such as "vnd.api+json", in lower case.
+	Subtype string
+	// Parameters, such as "charset", keyed by their lower-cased name.
+	Params map[string]string
+}
+
+func (h ContentType) Name() string {
+	return "Content-Type"
+}
+
+func (h ContentType) Value() string {
+	var b strings.Builder
+	b.WriteString(h.Essence())
+	keys := make([]string, 0, len(h.Params))
+	for k := range h.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString("; " + k + "=" + quoteIfNeeded(h.Params[k]))
+	}
+	return b.String()
+}
+
+// Parse parses a MIME type the way browsers do.
+//
+// https://mimesniff.spec.whatwg.org/#parse-a-mime-type
+func (h *ContentType) Parse(hdr string) error {
+	s := strings.Trim(hdr, httpWhitespace)
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return fmt.Errorf("The value for Content-Type must be a MIME type; got %s", hdr)
+	}
+	typ, s := s[:slash], s[slash+1:]
+	subtype := s
+	if semi := strings.IndexByte(s, ';'); semi >= 0 {
+		subtype, s = s[:semi], s[semi:]
+	} else {
+		s = ""
+	}
+	subtype = strings.TrimRight(subtype, httpWhitespace)
+	if !isToken(typ) || !isToken(subtype) {
+		return fmt.Errorf("The value for Content-Type must be a MIME type; got %s", hdr)
+	}
+	val := ContentType{Type: strings.ToLower(typ), Subtype: strings.ToLower(subtype)}
+
+	for len(s) > 0 {
+		// Skip the ';' and any whitespace after it.
+		s = strings.TrimLeft(s[1:], httpWhitespace)
+		end := strings.IndexAny(s, ";=")
+		if end < 0 {
+			break
+		}
+		name := strings.ToLower(s[:end])
+		s = s[end:]
+		if s[0] == ';' {
+			continue
+		}
+		s = s[1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			value, s = collectQuoted(s)
+			if semi := strings.IndexByte(s, ';'); semi >= 0 {
+				s = s[semi:]
+			} else {
+				s = ""
+			}
+		} else {
+			value = s
+			if semi := strings.IndexByte(s, ';'); semi >= 0 {
+				value, s = s[:semi], s[semi:]
+			} else {
+				s = ""
+			}
+			value = strings.TrimRight(value, httpWhitespace)
+			if value == "" {
+				continue
+			}
+		}
+		if _, dup := val.Params[name]; !dup && isToken(name) && isQuotedStringText(value) {
+			if val.Params == nil {
+				val.Params = map[string]string{}
+			}
+			val.Params[name] = value
+		}
+	}
+	*h = val
+	return nil
+}
+
+// ParseStrict parses a media type according to the RFC 9110 grammar,
+// rejecting anything browsers would let slide: malformed or duplicated
+// parameters, empty values and unterminated quoted strings.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-8.3.1
+func (h *ContentType) ParseStrict(hdr string) error {
+	s := strings.Trim(hdr, " \t")
+	invalid := fmt.Errorf("The value for Content-Type must be a valid media type; got %s", hdr)
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return invalid
+	}
+	typ, s := s[:slash], s[slash+1:]
+	end := strings.IndexAny(s, " \t;")
+	if end < 0 {
+		end = len(s)
+	}
+	subtype, s := s[:end], s[end:]
+	if !isToken(typ) || !isToken(subtype) {
+		return invalid
+	}
+	val := ContentType{Type: strings.ToLower(typ), Subtype: strings.ToLower(subtype)}
+
+	for s = strings.TrimLeft(s, " \t"); len(s) > 0; s = strings.TrimLeft(s, " \t") {
+		if s[0] != ';' {
+			return invalid
+		}
+		s = strings.TrimLeft(s[1:], " \t")
+		if s == "" || s[0] == ';' {
+			// Empty parameters are allowed by the grammar.
+			continue
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 || !isToken(s[:eq]) {
+			return invalid
+		}
+		name := strings.ToLower(s[:eq])
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var ok bool
+			if value, s, ok = parseQuotedString(s); !ok {
+				return invalid
+			}
+		} else {
+			end := strings.IndexAny(s, " \t;")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+			if !isToken(value) {
+				return invalid
+			}
+		}
+		if _, dup := val.Params[name]; dup {
+			return fmt.Errorf("Duplicate parameter in Content-Type: %s", name)
+		}
+		if val.Params == nil {
+			val.Params = map[string]string{}
+		}
+		val.Params[name] = value
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentType{}
+
+// Essence returns the type and subtype, without parameters, such as
+// "text/html".
+//
+// https://mimesniff.spec.whatwg.org/#mime-type-essence
+func (h ContentType) Essence() string {
+	return h.Type + "/" + h.Subtype
+}
+
+// Is reports whether the media type has the given essence, compared
+// case-insensitively. Parameters of mediaType are ignored.
+func (h ContentType) Is(mediaType string) bool {
+	var other ContentType
+	if other.Parse(mediaType) != nil {
+		return false
+	}
+	return strings.EqualFold(h.Essence(), other.Essence())
+}
+
+// Suffix returns the structured syntax suffix of the subtype, such as "json"
+// for "application/vnd.api+json", or "" if there is none.
+//
+// https://www.rfc-editor.org/rfc/rfc6838#section-4.2.8
+func (h ContentType) Suffix() string {
+	if plus := strings.LastIndexByte(h.Subtype, '+'); plus >= 0 {
+		return h.Subtype[plus+1:]
+	}
+	return ""
+}
+
+// Charset returns the value of the charset parameter, or "".
+func (h ContentType) Charset() string {
+	return h.Params["charset"]
+}
+
+// IsJSON reports whether the media type is a JSON MIME type: one with a
+// "+json" suffix, or whose essence is "application/json" or "text/json".
+//
+// https://mimesniff.spec.whatwg.org/#json-mime-type
+func (h ContentType) IsJSON() bool {
+	return h.Suffix() == "json" || h.Is("application/json") || h.Is("text/json")
+}
+
+// IsXML reports whether the media type is an XML MIME type: one with a
+// "+xml" suffix, or whose essence is "application/xml" or "text/xml".
+//
+// https://mimesniff.spec.whatwg.org/#xml-mime-type
+func (h ContentType) IsXML() bool {
+	return h.Suffix() == "xml" || h.Is("application/xml") || h.Is("text/xml")
+}
+
+// SetContentType sets the Content-Type of a response along with
+// "X-Content-Type-Options: nosniff", so that browsers trust the declared
+// type rather than guessing from the content.
+func SetContentType(w http.ResponseWriter, h ContentType) {
+	w.Header().Set(h.Name(), h.Value())
+	opts := ContentTypeOptions{}
+	w.Header().Set(opts.Name(), opts.Value())
+}
+
+// The HTTP whitespace code points, as defined by the Fetch standard.
+const httpWhitespace = " \t\r\n"
+
+// isQuotedStringText reports whether every byte of s may appear in a quoted
+// string.
+func isQuotedStringText(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\t' && (s[i] < ' ' || s[i] == 0x7f) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectQuoted collects an HTTP quoted string the WHATWG way: escapes are
+// resolved, and an unterminated string runs to the end of the input. It
+// returns the value and the rest of the input, after the closing quote.
+//
+// https://fetch.spec.whatwg.org/#collect-an-http-quoted-string
+func collectQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:]
+		case '\\':
+			if i+1 == len(s) {
+				b.WriteByte('\\')
+				return b.String(), ""
+			}
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), ""
+}
+
+// parseQuotedString parses an RFC 9110 quoted string, returning its value,
+// the rest of the input and whether it was well-formed.
+func parseQuotedString(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), s[i+1:], true
+		case c == '\\':
+			if i+1 == len(s) {
+				return "", "", false
+			}
+			i++
+			c = s[i]
+		}
+		if c != '\t' && (c < ' ' || c == 0x7f) {
+			return "", "", false
+		}
+		b.WriteByte(c)
+	}
+	return "", "", false
+}
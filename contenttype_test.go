@@ -0,0 +1,110 @@
+package headers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestContentType(t *testing.T) {
+	verify(t, []testcase{
+		{&ContentType{Type: "text", Subtype: "html"}, "text/html"},
+		{&ContentType{Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8"}}, "text/html; charset=utf-8"},
+		{&ContentType{Type: "multipart", Subtype: "form-data", Params: map[string]string{"boundary": "a b", "charset": "utf-8"}}, `multipart/form-data; boundary="a b"; charset=utf-8`},
+	})
+}
+
+func TestContentTypeParse(t *testing.T) {
+	for hdr, expected := range map[string]ContentType{
+		"Text/HTML":                           {Type: "text", Subtype: "html"},
+		" text/html ; Charset=UTF-8 ":         {Type: "text", Subtype: "html", Params: map[string]string{"charset": "UTF-8"}},
+		`text/html;charset="utf-8";charset=x`: {Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8"}},
+		`text/html;charset="utf\-8" junk;a=b`: {Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8", "a": "b"}},
+		`text/html;charset="unterminated`:     {Type: "text", Subtype: "html", Params: map[string]string{"charset": "unterminated"}},
+		"text/html;;charset;a=;b=c":           {Type: "text", Subtype: "html", Params: map[string]string{"b": "c"}},
+		"text/html;bad name=x;a=\x01;c=d":     {Type: "text", Subtype: "html", Params: map[string]string{"c": "d"}},
+		`application/vnd.api+json; ext="a;b"`: {Type: "application", Subtype: "vnd.api+json", Params: map[string]string{"ext": "a;b"}},
+	} {
+		var h ContentType
+		if err := h.Parse(hdr); err != nil {
+			t.Errorf("%q: %s", hdr, err)
+		} else if !reflect.DeepEqual(h, expected) {
+			t.Errorf("%q: expected %+v, got %+v", hdr, expected, h)
+		}
+	}
+	for _, hdr := range []string{"", "text", "/html", "text/", "te xt/html", "text/ht(ml", "text/html x"} {
+		var h ContentType
+		if err := h.Parse(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+}
+
+func TestContentTypeParseStrict(t *testing.T) {
+	for hdr, expected := range map[string]ContentType{
+		"Text/HTML":                         {Type: "text", Subtype: "html"},
+		"text/html ; Charset=UTF-8":         {Type: "text", Subtype: "html", Params: map[string]string{"charset": "UTF-8"}},
+		`text/html;charset="utf\-8";;a=b ;`: {Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8", "a": "b"}},
+	} {
+		var h ContentType
+		if err := h.ParseStrict(hdr); err != nil {
+			t.Errorf("%q: %s", hdr, err)
+		} else if !reflect.DeepEqual(h, expected) {
+			t.Errorf("%q: expected %+v, got %+v", hdr, expected, h)
+		}
+	}
+	for _, hdr := range []string{
+		"text",
+		"text/html x",
+		"text/html;charset",
+		"text/html;charset=",
+		`text/html;charset="unterminated`,
+		`text/html;charset="utf-8" junk`,
+		"text/html;charset=utf-8;charset=utf-8",
+		"text/html;bad name=x",
+		"text/html;a=\x01",
+	} {
+		var h ContentType
+		if err := h.ParseStrict(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+}
+
+func TestContentTypeHelpers(t *testing.T) {
+	for hdr, expected := range map[string][]interface{}{
+		"application/json":              {"application/json", "", true, false},
+		"application/vnd.api+json":      {"application/vnd.api+json", "json", true, false},
+		"TEXT/JSON; charset=utf-8":      {"text/json", "", true, false},
+		"application/atom+xml":          {"application/atom+xml", "xml", false, true},
+		"text/xml":                      {"text/xml", "", false, true},
+		"application/jsonp":             {"application/jsonp", "", false, false},
+		"application/problem+json+what": {"application/problem+json+what", "what", false, false},
+	} {
+		var h ContentType
+		if err := h.Parse(hdr); err != nil {
+			t.Fatal(err)
+		}
+		got := []interface{}{h.Essence(), h.Suffix(), h.IsJSON(), h.IsXML()}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", hdr, expected, got)
+		}
+	}
+
+	h := ContentType{Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8"}}
+	if !h.Is("Text/HTML; charset=latin1") || h.Is("text/plain") || h.Is("garbage") {
+		t.Errorf("unexpected essence comparison")
+	}
+	if h.Charset() != "utf-8" || (ContentType{}).Charset() != "" {
+		t.Errorf("unexpected charset")
+	}
+}
+
+func TestSetContentType(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "text/plain")
+	SetContentType(w, ContentType{Type: "application", Subtype: "json"})
+	if w.Header().Get("Content-Type") != "application/json" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+}
@@ -1 +1,74 @@
 package headers
+
+import (
+	"strings"
+)
+
+// A PolicyDirective is a single directive of a Content-Security-Policy, such
+// as "default-src 'self'", with its name and source expressions.
+type PolicyDirective struct {
+	Name   string
+	Values []string
+}
+
+// The HTTP Content-Security-Policy response header allows web site
+// administrators to control resources the user agent is allowed to load for a
+// given page. With a few exceptions, policies mostly involve specifying server
+// origins and script endpoints. This helps guard against cross-site scripting
+// attacks (XSS).
+//
+// https://mdn.io/Content-Security-Policy
+type ContentSecurityPolicy struct {
+	Directives []PolicyDirective
+}
+
+func (h ContentSecurityPolicy) Name() string {
+	return "Content-Security-Policy"
+}
+
+func (h ContentSecurityPolicy) Value() string {
+	v := make([]string, len(h.Directives))
+	for i, d := range h.Directives {
+		v[i] = strings.Join(append([]string{d.Name}, d.Values...), " ")
+	}
+	return strings.Join(v, "; ")
+}
+
+// Parse parses a serialized policy the way browsers do: directive names are
+// case-insensitive, and repeated directives are ignored.
+//
+// https://www.w3.org/TR/CSP3/#parse-serialized-policy
+func (h *ContentSecurityPolicy) Parse(hdr string) error {
+	val := ContentSecurityPolicy{}
+	seen := map[string]bool{}
+	for _, token := range strings.Split(hdr, ";") {
+		fields := strings.Fields(token)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		d := PolicyDirective{Name: name}
+		if len(fields) > 1 {
+			d.Values = fields[1:]
+		}
+		val.Directives = append(val.Directives, d)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentSecurityPolicy{}
+
+// Directive returns the named directive, and whether the policy has it.
+func (h ContentSecurityPolicy) Directive(name string) (PolicyDirective, bool) {
+	for _, d := range h.Directives {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return PolicyDirective{}, false
+}
@@ -0,0 +1,33 @@
+package headers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContentSecurityPolicy(t *testing.T) {
+	verify(t, []testcase{
+		{&ContentSecurityPolicy{}, ""},
+		{&ContentSecurityPolicy{Directives: []PolicyDirective{{Name: "sandbox"}}}, "sandbox"},
+		{&ContentSecurityPolicy{Directives: []PolicyDirective{
+			{Name: "default-src", Values: []string{"'self'"}},
+			{Name: "img-src", Values: []string{"'self'", "https://example.com"}},
+		}}, "default-src 'self'; img-src 'self' https://example.com"},
+	})
+
+	var h ContentSecurityPolicy
+	h.Parse("Script-Src 'self';; script-src *; sandbox allow-forms ")
+	expected := []PolicyDirective{
+		{Name: "script-src", Values: []string{"'self'"}},
+		{Name: "sandbox", Values: []string{"allow-forms"}},
+	}
+	if !reflect.DeepEqual(h.Directives, expected) {
+		t.Errorf("unexpected directives %+v", h.Directives)
+	}
+	if d, ok := h.Directive("SANDBOX"); !ok || d.Values[0] != "allow-forms" {
+		t.Errorf("expected sandbox directive")
+	}
+	if _, ok := h.Directive("img-src"); ok {
+		t.Errorf("unexpected img-src directive")
+	}
+}
@@ -0,0 +1,145 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// formatHTTPDate formats t as an IMF-fixdate, the preferred format for
+// HTTP-date values.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-5.6.7
+func formatHTTPDate(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// parseHTTPDate parses an HTTP-date in any of the three formats recipients
+// are required to accept: IMF-fixdate, the obsolete RFC 850 format and ANSI
+// C's asctime() format.
+func parseHTTPDate(name, hdr string) (time.Time, error) {
+	t, err := http.ParseTime(hdr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s value; got %s", name, hdr)
+	}
+	return t, nil
+}
+
+// The Last-Modified response HTTP header contains a date and time when the
+// origin server believes the resource was last modified. It is used as a
+// validator to determine if the resource is the same as the previously stored
+// one. Less accurate than an ETag header, it is a fallback mechanism.
+//
+// https://mdn.io/Last-Modified
+type LastModified struct {
+	Time time.Time
+}
+
+func (h LastModified) Name() string {
+	return "Last-Modified"
+}
+
+func (h LastModified) Value() string {
+	return formatHTTPDate(h.Time)
+}
+
+func (h *LastModified) Parse(hdr string) error {
+	t, err := parseHTTPDate(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	*h = LastModified{t}
+	return nil
+}
+
+var _ Header = &LastModified{}
+
+// LastModifiedAt returns a Last-Modified header for t, truncated to the
+// second granularity of HTTP-date. A modification time in the future is
+// replaced by the current time, as origin servers must not send a
+// Last-Modified date later than their own Date.
+func LastModifiedAt(t time.Time) *LastModified {
+	if now := time.Now(); t.After(now) {
+		t = now
+	}
+	return &LastModified{t.UTC().Truncate(time.Second)}
+}
+
+// LastModifiedFile returns a Last-Modified header for the modification time
+// of a file.
+func LastModifiedFile(fi os.FileInfo) *LastModified {
+	return LastModifiedAt(fi.ModTime())
+}
+
+// The If-Modified-Since request HTTP header makes the request conditional:
+// the server will send back the requested resource, with a 200 status, only
+// if it has been last modified after the given date. If the resource has not
+// been modified since, the response will be a 304 without any body.
+//
+// https://mdn.io/If-Modified-Since
+type IfModifiedSince struct {
+	Time time.Time
+}
+
+func (h IfModifiedSince) Name() string {
+	return "If-Modified-Since"
+}
+
+func (h IfModifiedSince) Value() string {
+	return formatHTTPDate(h.Time)
+}
+
+func (h *IfModifiedSince) Parse(hdr string) error {
+	t, err := parseHTTPDate(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	*h = IfModifiedSince{t}
+	return nil
+}
+
+var _ Header = &IfModifiedSince{}
+
+// Modified reports whether a representation last modified at t has changed
+// since the date in the header. Times are compared at second granularity.
+func (h IfModifiedSince) Modified(t time.Time) bool {
+	return t.Truncate(time.Second).After(h.Time)
+}
+
+// The If-Unmodified-Since request HTTP header makes the request conditional:
+// the server will send back the requested resource, or accept it in the case
+// of a POST or another non-safe method, only if it has not been last modified
+// after the given date. If the resource has been modified after the given
+// date, the response will be a 412 (Precondition Failed) error.
+//
+// https://mdn.io/If-Unmodified-Since
+type IfUnmodifiedSince struct {
+	Time time.Time
+}
+
+func (h IfUnmodifiedSince) Name() string {
+	return "If-Unmodified-Since"
+}
+
+func (h IfUnmodifiedSince) Value() string {
+	return formatHTTPDate(h.Time)
+}
+
+func (h *IfUnmodifiedSince) Parse(hdr string) error {
+	t, err := parseHTTPDate(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	*h = IfUnmodifiedSince{t}
+	return nil
+}
+
+var _ Header = &IfUnmodifiedSince{}
+
+// Unmodified reports whether a representation last modified at t has not
+// changed since the date in the header. Times are compared at second
+// granularity.
+func (h IfUnmodifiedSince) Unmodified(t time.Time) bool {
+	return !t.Truncate(time.Second).After(h.Time)
+}
@@ -0,0 +1,87 @@
+package headers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDateValidators(t *testing.T) {
+	modified := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+	verify(t, []testcase{
+		{&LastModified{modified}, "Sun, 06 Nov 1994 08:49:37 GMT"},
+		{&IfModifiedSince{modified}, "Sun, 06 Nov 1994 08:49:37 GMT"},
+		{&IfUnmodifiedSince{modified}, "Sun, 06 Nov 1994 08:49:37 GMT"},
+	})
+}
+
+func TestHTTPDateFormats(t *testing.T) {
+	expected := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+	for _, hdr := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+	} {
+		var h IfModifiedSince
+		if err := h.Parse(hdr); err != nil {
+			t.Fatalf("%s: %s", hdr, err)
+		}
+		if !h.Time.Equal(expected) {
+			t.Errorf("%s: expected %s, got %s", hdr, expected, h.Time)
+		}
+	}
+	var h LastModified
+	if err := h.Parse("0"); err == nil {
+		t.Errorf("expected err")
+	}
+}
+
+func TestDateValidatorComparison(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ims := IfModifiedSince{since}
+	ius := IfUnmodifiedSince{since}
+	for _, c := range []struct {
+		Modified time.Time
+		Expected bool
+	}{
+		{since.Add(-time.Second), false},
+		{since, false},
+		{since.Add(999 * time.Millisecond), false},
+		{since.Add(time.Second), true},
+	} {
+		if ims.Modified(c.Modified) != c.Expected {
+			t.Errorf("If-Modified-Since %s: expected %v", c.Modified, c.Expected)
+		}
+		if ius.Unmodified(c.Modified) == c.Expected {
+			t.Errorf("If-Unmodified-Since %s: expected %v", c.Modified, !c.Expected)
+		}
+	}
+}
+
+func TestLastModifiedAt(t *testing.T) {
+	past := time.Date(2020, 1, 1, 0, 0, 0, 5e8, time.UTC)
+	if lm := LastModifiedAt(past); !lm.Time.Equal(past.Truncate(time.Second)) {
+		t.Errorf("expected %s, got %s", past.Truncate(time.Second), lm.Time)
+	}
+	if lm := LastModifiedAt(time.Now().Add(time.Hour)); lm.Time.After(time.Now()) {
+		t.Errorf("expected future time to be clamped, got %s", lm.Time)
+	}
+
+	f, err := ioutil.TempFile("", "lastmodified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	if err := os.Chtimes(f.Name(), past, past); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lm := LastModifiedFile(fi); lm.Value() != "Wed, 01 Jan 2020 00:00:00 GMT" {
+		t.Errorf("unexpected Last-Modified %s", lm.Value())
+	}
+}
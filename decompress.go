@@ -0,0 +1,115 @@
+package headers
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decompress is an http.Handler middleware which decodes request bodies sent
+// with a Content-Encoding, so that the wrapped handler always reads the
+// original content. Stacked codings, such as "deflate, gzip", are decoded in
+// reverse order.
+//
+// Requests with a coding other than gzip, deflate or identity, or with more
+// than maxStackedCodings codings, are answered with 415 (Unsupported Media
+// Type) and an Accept-Encoding header listing the supported codings. Bodies
+// which fail to decode are answered with 400 (Bad Request).
+//
+// Reading more than MaxSize decoded bytes from the body fails, as it would
+// with http.MaxBytesReader, to defend against decompression bombs.
+type Decompress struct {
+	// The largest decoded body, in bytes. If zero, DefaultDecompressMaxSize
+	// is used.
+	MaxSize int64
+}
+
+// The largest request body Decompress decodes by default.
+const DefaultDecompressMaxSize = 10 << 20
+
+// The most codings Decompress will undo for a single request.
+const maxStackedCodings = 4
+
+// Handler wraps next with the middleware.
+func (d Decompress) Handler(next http.Handler) http.Handler {
+	if d.MaxSize == 0 {
+		d.MaxSize = DefaultDecompressMaxSize
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ce ContentEncoding
+		hdr := strings.Join(r.Header.Values(ce.Name()), ", ")
+		if hdr == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var codings []string
+		err := ce.Parse(hdr)
+		for _, coding := range ce.Codings {
+			if coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+		if err != nil || len(codings) > maxStackedCodings || !supportedCodings(codings) {
+			accept := AcceptEncoding{Codings: []Coding{{Name: "gzip", Q: 1}, {Name: "deflate", Q: 1}}}
+			w.Header().Set(accept.Name(), accept.Value())
+			http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body := &decodedBody{Reader: r.Body, closers: []io.Closer{r.Body}}
+		for i := len(codings) - 1; i >= 0; i-- {
+			var rc io.ReadCloser
+			switch codings[i] {
+			case "gzip", "x-gzip":
+				rc, err = gzip.NewReader(body.Reader)
+			case "deflate":
+				rc, err = zlib.NewReader(body.Reader)
+			}
+			if err != nil {
+				body.Close()
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			body.Reader = rc
+			body.closers = append(body.closers, rc)
+		}
+
+		r = r.Clone(r.Context())
+		r.Header.Del(ce.Name())
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		r.Body = http.MaxBytesReader(w, body, d.MaxSize)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// supportedCodings reports whether Decompress can decode every coding.
+func supportedCodings(codings []string) bool {
+	for _, coding := range codings {
+		switch coding {
+		case "gzip", "x-gzip", "deflate":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// decodedBody reads a request body through its decoders, and closes them
+// all along with the original body.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package headers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func encode(t *testing.T, body []byte, codings ...string) []byte {
+	for _, coding := range codings {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch coding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		default:
+			t.Fatalf("unknown coding %s", coding)
+		}
+		w.Write(body)
+		w.Close()
+		body = buf.Bytes()
+	}
+	return body
+}
+
+func TestDecompress(t *testing.T) {
+	var got []byte
+	var readErr error
+	h := Decompress{MaxSize: 1000}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Errorf("unexpected request headers %v", r.Header)
+		}
+		got, readErr = ioutil.ReadAll(r.Body)
+	}))
+	send := func(hdr string, body []byte) *httptest.ResponseRecorder {
+		got, readErr = nil, nil
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		if hdr != "" {
+			r.Header.Set("Content-Encoding", hdr)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	body := []byte(strings.Repeat("hello ", 100))
+	for hdr, codings := range map[string][]string{
+		"gzip":                 {"gzip"},
+		"X-Gzip":               {"gzip"},
+		"deflate":              {"deflate"},
+		"deflate, gzip":        {"deflate", "gzip"},
+		"gzip, identity, gzip": {"gzip", "gzip"},
+	} {
+		w := send(hdr, encode(t, body, codings...))
+		if w.Code != http.StatusOK || readErr != nil || !bytes.Equal(got, body) {
+			t.Errorf("%q: unexpected result %d %v %q", hdr, w.Code, readErr, got)
+		}
+	}
+	if send("", body); !bytes.Equal(got, body) {
+		t.Errorf("expected unencoded body to pass through")
+	}
+
+	if send("gzip", encode(t, bytes.Repeat([]byte("a"), 1001), "gzip")); readErr == nil {
+		t.Errorf("expected error reading oversized body")
+	}
+
+	for _, hdr := range []string{"br", "gzip, zstd", "gzip, gzip, gzip, gzip, gzip", "gzip/1"} {
+		w := send(hdr, body)
+		if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Encoding") != "gzip, deflate" {
+			t.Errorf("%q: expected 415, got %d %v", hdr, w.Code, w.Header())
+		}
+	}
+	if w := send("gzip", body); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for undecodable body, got %d", w.Code)
+	}
+}
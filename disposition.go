@@ -0,0 +1,173 @@
+package headers
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// The content is displayed in the browser, as part of a web page or as
+	// the web page.
+	DispositionInline = "inline"
+	// The content is downloaded and saved locally.
+	DispositionAttachment = "attachment"
+	// The content is a field of a multipart/form-data body.
+	DispositionFormData = "form-data"
+)
+
+// In a regular HTTP response, the Content-Disposition response header is a
+// header indicating if the content is expected to be displayed inline in the
+// browser, that is, as a Web page or as part of a Web page, or as an
+// attachment, that is downloaded and saved locally.
+//
+// Filenames which aren't plain ASCII are sent twice: as a UTF-8 filename*
+// parameter, and as an ASCII approximation for older clients. When parsing,
+// filename* takes precedence, and the filename is reduced to a base name
+// without control characters, so that it is safe to use locally.
+//
+// https://mdn.io/Content-Disposition
+type ContentDisposition struct {
+	// The disposition type, such as DispositionAttachment, in lower case.
+	Type string
+	// The name of the form field, for DispositionFormData.
+	FieldName string
+	// The suggested filename.
+	Filename string
+}
+
+func (h ContentDisposition) Name() string {
+	return "Content-Disposition"
+}
+
+func (h ContentDisposition) Value() string {
+	v := h.Type
+	if h.FieldName != "" {
+		v += "; name=" + quoteString(h.FieldName)
+	}
+	if h.Filename != "" {
+		if fallback := asciiFilename(h.Filename); fallback == h.Filename {
+			v += "; filename=" + quoteString(h.Filename)
+		} else {
+			v += "; filename=" + quoteString(fallback) + "; filename*=" + encodeExtValue(h.Filename)
+		}
+	}
+	return v
+}
+
+func (h *ContentDisposition) Parse(hdr string) error {
+	typ, rest := hdr, ""
+	if semi := strings.IndexByte(hdr, ';'); semi >= 0 {
+		typ, rest = hdr[:semi], hdr[semi+1:]
+	}
+	typ = strings.TrimSpace(typ)
+	if !isToken(typ) {
+		return fmt.Errorf("The value for Content-Disposition must start with a disposition type; got %s", hdr)
+	}
+	params, err := parseDirectiveList(rest, ';')
+	if err != nil {
+		return fmt.Errorf("Invalid parameters in Content-Disposition: %s", hdr)
+	}
+	val := ContentDisposition{Type: strings.ToLower(typ)}
+	var extended bool
+	for _, p := range params {
+		switch strings.ToLower(p.name) {
+		case "name":
+			val.FieldName = p.value
+		case "filename":
+			if !extended {
+				val.Filename = p.value
+			}
+		case "filename*":
+			// Values in unsupported charsets or with bad encoding are ignored,
+			// leaving the plain filename.
+			if filename, ok := decodeExtValue(p.value); ok {
+				val.Filename = filename
+				extended = true
+			}
+		}
+	}
+	val.Filename = sanitizeFilename(val.Filename)
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentDisposition{}
+
+// quoteString returns s as an HTTP quoted string.
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// asciiFilename approximates a filename in printable ASCII, replacing other
+// characters with underscores.
+func asciiFilename(filename string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r >= 0x7f {
+			return '_'
+		}
+		return r
+	}, filename)
+}
+
+// encodeExtValue encodes a UTF-8 string as an RFC 8187 ext-value.
+//
+// https://www.rfc-editor.org/rfc/rfc8187#section-3.2
+func encodeExtValue(s string) string {
+	var b strings.Builder
+	b.WriteString("UTF-8''")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x80 && isTokenChar(c) && c != '*' && c != '\'' && c != '%' && c != ':' && c != '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// decodeExtValue decodes an RFC 8187 ext-value in the UTF-8 or ISO-8859-1
+// charset. The language tag is ignored.
+func decodeExtValue(v string) (string, bool) {
+	parts := strings.SplitN(v, "'", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+	decoded, err := url.PathUnescape(parts[2])
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parts[0]) {
+	case "utf-8":
+		return decoded, utf8.ValidString(decoded)
+	case "iso-8859-1":
+		runes := make([]rune, len(decoded))
+		for i := 0; i < len(decoded); i++ {
+			runes[i] = rune(decoded[i])
+		}
+		return string(runes), true
+	}
+	return "", false
+}
+
+// sanitizeFilename reduces a suggested filename to something safe to save
+// as: its last path element, without control characters. Names which
+// would refer to a directory are dropped.
+func sanitizeFilename(filename string) string {
+	filename = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f || r >= 0x80 && r < 0xa0 {
+			return -1
+		}
+		return r
+	}, filename)
+	filename = path.Base(strings.Replace(filename, `\`, "/", -1))
+	filename = strings.TrimSpace(filename)
+	switch filename {
+	case ".", "..", "/":
+		return ""
+	}
+	return filename
+}
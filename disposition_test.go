@@ -0,0 +1,47 @@
+package headers
+
+import (
+	"testing"
+)
+
+func TestContentDisposition(t *testing.T) {
+	verify(t, []testcase{
+		{&ContentDisposition{Type: DispositionInline}, "inline"},
+		{&ContentDisposition{Type: DispositionAttachment, Filename: "report.pdf"}, `attachment; filename="report.pdf"`},
+		{&ContentDisposition{Type: DispositionAttachment, Filename: `say "hi".txt`}, `attachment; filename="say \"hi\".txt"`},
+		{&ContentDisposition{Type: DispositionFormData, FieldName: "upload", Filename: "a.png"}, `form-data; name="upload"; filename="a.png"`},
+		{&ContentDisposition{Type: DispositionAttachment, Filename: "€ rates.txt"}, `attachment; filename="_ rates.txt"; filename*=UTF-8''%E2%82%AC%20rates.txt`},
+		{&ContentDisposition{Type: DispositionAttachment, Filename: "日本語.pdf"}, `attachment; filename="___.pdf"; filename*=UTF-8''%E6%97%A5%E6%9C%AC%E8%AA%9E.pdf`},
+	})
+}
+
+func TestContentDispositionParse(t *testing.T) {
+	for hdr, expected := range map[string]ContentDisposition{
+		"Attachment":                      {Type: "attachment"},
+		`attachment; filename=plain.txt`:  {Type: "attachment", Filename: "plain.txt"},
+		`attachment; FILENAME="a\"b.txt"`: {Type: "attachment", Filename: `a"b.txt`},
+		`attachment; filename*=UTF-8''%E2%82%AC.txt; filename="fallback.txt"`:   {Type: "attachment", Filename: "€.txt"},
+		`attachment; filename="fallback.txt"; filename*=utf-8'en'%E2%82%AC.txt`: {Type: "attachment", Filename: "€.txt"},
+		`attachment; filename*=iso-8859-1''%A3.txt`:                             {Type: "attachment", Filename: "£.txt"},
+		`attachment; filename="ok.txt"; filename*=koi8-r''%E2.txt`:              {Type: "attachment", Filename: "ok.txt"},
+		`attachment; filename="ok.txt"; filename*=UTF-8''%FF.txt`:               {Type: "attachment", Filename: "ok.txt"},
+		`attachment; filename="../../etc/passwd"`:                               {Type: "attachment", Filename: "passwd"},
+		`attachment; filename="C:\\Windows\\evil.exe"`:                          {Type: "attachment", Filename: "evil.exe"},
+		`attachment; filename*=UTF-8''a%0Ab%00.txt`:                             {Type: "attachment", Filename: "ab.txt"},
+		`attachment; filename=".."`:                                             {Type: "attachment"},
+		`form-data; name="field"; filename="/"`:                                 {Type: "form-data", FieldName: "field"},
+	} {
+		var h ContentDisposition
+		if err := h.Parse(hdr); err != nil {
+			t.Errorf("%q: %s", hdr, err)
+		} else if h != expected {
+			t.Errorf("%q: expected %+v, got %+v", hdr, expected, h)
+		}
+	}
+	for _, hdr := range []string{"", "; filename=a", "attach ment", `attachment; filename="unterminated`} {
+		var h ContentDisposition
+		if err := h.Parse(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// A Coding is a member of an Accept-Encoding header: a content coding, such
+// as "gzip", "identity" or "*", and a weight.
+type Coding struct {
+	Name string
+	// The relative preference for the coding, between 0 and 1. A weight of 0
+	// means "not acceptable".
+	Q float64
+}
+
+func (c Coding) String() string {
+	return formatWeighted(c.Name, nil, c.Q)
+}
+
+// The Accept-Encoding request HTTP header advertises which content encoding,
+// usually a compression algorithm, the client is able to understand. Using
+// content negotiation, the server selects one of the proposals, uses it and
+// informs the client of its choice with the Content-Encoding response header.
+//
+// An empty header means that only the identity encoding is acceptable.
+//
+// https://mdn.io/Accept-Encoding
+type AcceptEncoding struct {
+	Codings []Coding
+}
+
+func (h AcceptEncoding) Name() string {
+	return "Accept-Encoding"
+}
+
+func (h AcceptEncoding) Value() string {
+	v := make([]string, len(h.Codings))
+	for i, c := range h.Codings {
+		v[i] = c.String()
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *AcceptEncoding) Parse(hdr string) error {
+	list, err := parseWeightedList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	val := AcceptEncoding{}
+	for _, w := range list {
+		if !isToken(w.value) {
+			return fmt.Errorf("Invalid coding in Accept-Encoding: %s", w.value)
+		}
+		val.Codings = append(val.Codings, Coding{Name: strings.ToLower(w.value), Q: w.q})
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &AcceptEncoding{}
+
+// Quality returns the weight the header gives to a content coding. Codings
+// not listed take the weight of "*", if present. The identity coding is
+// acceptable unless excluded by "identity;q=0" or "*;q=0".
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-12.5.3
+func (h AcceptEncoding) Quality(coding string) float64 {
+	coding = strings.ToLower(coding)
+	wildcard := -1.0
+	for _, c := range h.Codings {
+		if c.Name == coding || coding == "gzip" && c.Name == "x-gzip" {
+			return c.Q
+		}
+		if c.Name == "*" {
+			wildcard = c.Q
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard
+	}
+	if coding == "identity" {
+		return 1
+	}
+	return 0
+}
+
+// NegotiateEncoding picks the content coding to apply to a response, from
+// the codings the server offers in order of preference. The identity coding
+// is always a candidate, and wins if the client prefers it. The result is
+// false if the client accepts neither the offers nor identity.
+//
+// Requests without a valid Accept-Encoding header get the identity coding:
+// although such clients accept any coding, many don't expect to.
+func NegotiateEncoding(r *http.Request, offers ...string) (string, bool) {
+	var accept AcceptEncoding
+	hdr, ok := r.Header[accept.Name()]
+	if !ok || accept.Parse(strings.Join(hdr, ", ")) != nil {
+		return "identity", true
+	}
+	choice := best(append(offers[:len(offers):len(offers)], "identity"), accept.Quality)
+	return choice, choice != ""
+}
+
+// The Content-Encoding entity header is used to compress the media-type. When
+// present, its value indicates which encodings were applied to the
+// entity-body, in the order they were applied. It lets the client know how
+// to decode in order to obtain the media-type referenced by the Content-Type
+// header.
+//
+// https://mdn.io/Content-Encoding
+type ContentEncoding struct {
+	Codings []string
+}
+
+func (h ContentEncoding) Name() string {
+	return "Content-Encoding"
+}
+
+func (h ContentEncoding) Value() string {
+	return strings.Join(h.Codings, ", ")
+}
+
+func (h *ContentEncoding) Parse(hdr string) error {
+	val := ContentEncoding{}
+	for _, coding := range splitList(hdr) {
+		if !isToken(coding) || coding == "*" {
+			return fmt.Errorf("Invalid coding in Content-Encoding: %s", coding)
+		}
+		val.Codings = append(val.Codings, strings.ToLower(coding))
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentEncoding{}
@@ -0,0 +1,114 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAcceptEncoding(t *testing.T) {
+	verify(t, []testcase{
+		{&AcceptEncoding{}, ""},
+		{&AcceptEncoding{Codings: []Coding{{Name: "gzip", Q: 1}}}, "gzip"},
+		{&AcceptEncoding{Codings: []Coding{
+			{Name: "br", Q: 1},
+			{Name: "gzip", Q: 0.8},
+			{Name: "identity", Q: 0.1},
+			{Name: "*", Q: 0},
+		}}, "br, gzip;q=0.8, identity;q=0.1, *;q=0"},
+	})
+
+	var h AcceptEncoding
+	for _, hdr := range []string{"gzip/1", "gzip;q=2", "gzip deflate"} {
+		if err := h.Parse(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+}
+
+func TestAcceptEncodingQuality(t *testing.T) {
+	for hdr, expected := range map[string]map[string]float64{
+		"":                      {"identity": 1, "gzip": 0},
+		"gzip":                  {"identity": 1, "gzip": 1, "GZIP": 1, "br": 0},
+		"x-gzip":                {"gzip": 1},
+		"*;q=0.5, br":           {"identity": 0.5, "gzip": 0.5, "br": 1},
+		"*;q=0":                 {"identity": 0, "gzip": 0},
+		"gzip, identity;q=0":    {"identity": 0, "gzip": 1},
+		"*;q=0, identity;q=0.2": {"identity": 0.2, "deflate": 0},
+	} {
+		var h AcceptEncoding
+		if err := h.Parse(hdr); err != nil {
+			t.Fatal(err)
+		}
+		for coding, q := range expected {
+			if got := h.Quality(coding); got != q {
+				t.Errorf("%q: expected %s to have quality %v, got %v", hdr, coding, q, got)
+			}
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offers := []string{"gzip", "deflate"}
+	for _, c := range []struct {
+		hdr      *string
+		expected string
+		ok       bool
+	}{
+		{nil, "identity", true},
+		{str(""), "identity", true},
+		{str("gzip, deflate, br"), "gzip", true},
+		{str("deflate, gzip;q=0.5"), "deflate", true},
+		{str("gzip;q=0.5, identity"), "identity", true},
+		{str("br"), "identity", true},
+		{str("br, identity;q=0"), "", false},
+		{str("*"), "gzip", true},
+		{str("*, gzip;q=0"), "deflate", true},
+		{str("invalid/coding"), "identity", true},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if c.hdr != nil {
+			r.Header["Accept-Encoding"] = []string{*c.hdr}
+		}
+		coding, ok := NegotiateEncoding(r, offers...)
+		if coding != c.expected || ok != c.ok {
+			t.Errorf("%v: expected %q %v, got %q %v", r.Header, c.expected, c.ok, coding, ok)
+		}
+	}
+	if len(offers) != 2 {
+		t.Errorf("offers were modified")
+	}
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestNegotiatorEncoding(t *testing.T) {
+	n := Negotiator{Encodings: []string{"gzip"}}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	if result, status := n.Negotiate(r); result.Encoding != "gzip" || status != http.StatusOK || !reflect.DeepEqual(result.Vary, []string{"Accept-Encoding"}) {
+		t.Errorf("unexpected result %+v %d", result, status)
+	}
+	r.Header.Set("Accept-Encoding", "br, *;q=0")
+	if result, status := n.Negotiate(r); status != http.StatusNotAcceptable {
+		t.Errorf("expected 406, got %+v %d", result, status)
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	verify(t, []testcase{
+		{&ContentEncoding{Codings: []string{"gzip"}}, "gzip"},
+		{&ContentEncoding{Codings: []string{"deflate", "gzip"}}, "deflate, gzip"},
+	})
+
+	var h ContentEncoding
+	if err := h.Parse("GZip,,Deflate"); err != nil || !reflect.DeepEqual(h.Codings, []string{"gzip", "deflate"}) {
+		t.Errorf("unexpected codings %v: %v", h.Codings, err)
+	}
+	if err := h.Parse("*"); err == nil {
+		t.Errorf("expected error for wildcard Content-Encoding")
+	}
+}
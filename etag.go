@@ -0,0 +1,146 @@
+package headers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"net/http"
+	"strconv"
+)
+
+// AutoETag is an http.Handler middleware which computes an ETag from the body
+// of each response, and answers matching If-None-Match requests with 304 (Not
+// Modified), without the wrapped handler knowing about either.
+//
+// Responses are buffered until they are complete, or until they grow past
+// MaxSize or are flushed, at which point they are streamed through untouched. Responses which
+// already have an ETag, or aren't 200 (OK), are left alone. Responses with a
+// Content-Encoding get a weak ETag, as the same representation may be encoded
+// differently.
+type AutoETag struct {
+	// Creates the hash used to compute ETags. If nil, SHA-256 is used.
+	Hash func() hash.Hash
+	// Generate weak ETags for every response.
+	Weak bool
+	// The largest response body, in bytes, for which an ETag is computed. If
+	// zero, DefaultAutoETagMaxSize is used.
+	MaxSize int
+}
+
+// The largest response body AutoETag buffers by default.
+const DefaultAutoETagMaxSize = 1 << 20
+
+// Handler wraps next with the middleware.
+func (a AutoETag) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handlers may skip writing the body of HEAD responses, so only GET
+		// responses are tagged.
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ew := &etagWriter{ResponseWriter: w, max: a.MaxSize, status: http.StatusOK}
+		if ew.max == 0 {
+			ew.max = DefaultAutoETagMaxSize
+		}
+		next.ServeHTTP(ew, r)
+		if ew.passthrough {
+			return
+		}
+
+		h := w.Header()
+		if ew.status == http.StatusOK && h.Get("ETag") == "" {
+			etag := a.etag(ew.buf.Bytes(), h.Get("Content-Encoding") != "")
+			h.Set(etag.Name(), etag.Value())
+			var inm IfNoneMatch
+			if hdr := r.Header.Get(inm.Name()); hdr != "" && inm.Parse(hdr) == nil && inm.Matches(&etag) {
+				for _, name := range []string{"Content-Length", "Content-Type", "Content-Encoding"} {
+					h.Del(name)
+				}
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		if h.Get("Content-Length") == "" && bodyAllowed(ew.status) {
+			h.Set("Content-Length", strconv.Itoa(ew.buf.Len()))
+		}
+		w.WriteHeader(ew.status)
+		w.Write(ew.buf.Bytes())
+	})
+}
+
+func (a AutoETag) etag(body []byte, encoded bool) ETag {
+	newHash := a.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := newHash()
+	h.Write(body)
+	return ETag{
+		Tag:  base64.RawURLEncoding.EncodeToString(h.Sum(nil)),
+		Weak: a.Weak || encoded,
+	}
+}
+
+// etagWriter buffers a response until it is complete, or switches to
+// writing through once the body grows past max bytes.
+type etagWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	max         int
+	status      int
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) <= w.max {
+		return w.buf.Write(b)
+	}
+	// The response is too large to buffer: flush what we have and get out of
+	// the way.
+	if err := w.pass(); err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far to the client. The response is
+// no longer complete when buffered, so it is written through untagged.
+func (w *etagWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	if !w.passthrough {
+		w.pass()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// pass writes out the buffered response and switches to writing through.
+func (w *etagWriter) pass() error {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// bodyAllowed reports whether a response with the given status may have a
+// body.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
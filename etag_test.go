@@ -0,0 +1,106 @@
+package headers
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAutoETag(t *testing.T) {
+	h := AutoETag{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/tagged":
+			w.Header().Set("ETag", `"mine"`)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/unchanged":
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello, "))
+		w.Write([]byte("world"))
+	}))
+
+	w := serve(h, "GET", "/", nil)
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || w.Body.String() != "hello, world" || w.Header().Get("Content-Length") != "12" {
+		t.Fatalf("unexpected response %q %q", etag, w.Body.String())
+	}
+	if w := serve(h, "GET", "/", nil); w.Header().Get("ETag") != etag {
+		t.Errorf("expected a stable ETag")
+	}
+
+	w = serve(h, "GET", "/", map[string]string{"If-None-Match": `"other", W/` + etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("expected 304, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "" {
+		t.Errorf("expected no Content-Type on 304")
+	}
+
+	if w := serve(h, "GET", "/gzip", nil); !strings.HasPrefix(w.Header().Get("ETag"), `W/"`) {
+		t.Errorf("expected weak ETag for encoded response, got %q", w.Header().Get("ETag"))
+	}
+	if w := serve(h, "GET", "/tagged", map[string]string{"If-None-Match": etag}); w.Header().Get("ETag") != `"mine"` || w.Code != http.StatusOK {
+		t.Errorf("expected existing ETag to be kept, got %q", w.Header().Get("ETag"))
+	}
+	if w := serve(h, "GET", "/missing", nil); w.Header().Get("ETag") != "" || w.Code != http.StatusNotFound {
+		t.Errorf("expected no ETag on error responses")
+	}
+	for _, path := range []string{"/empty", "/unchanged"} {
+		if w := serve(h, "GET", path, nil); w.Header()["Content-Length"] != nil {
+			t.Errorf("%s: expected no Content-Length, got %q", path, w.Header().Get("Content-Length"))
+		}
+	}
+	if w := serve(h, "POST", "/", nil); w.Header().Get("ETag") != "" {
+		t.Errorf("expected no ETag on POST")
+	}
+}
+
+func TestAutoETagOptions(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 10; i++ {
+			w.Write([]byte(body[:10]))
+		}
+	})
+
+	w := serve(AutoETag{MaxSize: 50}.Handler(handler), "GET", "/", nil)
+	if w.Header().Get("ETag") != "" || w.Body.String() != body {
+		t.Errorf("expected large response to stream through, got %q", w.Header().Get("ETag"))
+	}
+
+	w = serve(AutoETag{Hash: md5.New, Weak: true}.Handler(handler), "GET", "/", nil)
+	sum := md5.Sum([]byte(body))
+	if etag := w.Header().Get("ETag"); etag != `W/"`+base64.RawURLEncoding.EncodeToString(sum[:])+`"` {
+		t.Errorf("unexpected ETag %q", etag)
+	}
+}
+
+func TestAutoETagFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var flushed string
+	h := AutoETag{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		flushed = rec.Body.String()
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if !rec.Flushed || flushed != "data: 1\n\n" {
+		t.Errorf("expected the first event to be flushed, got %q", flushed)
+	}
+	if rec.Header().Get("ETag") != "" || rec.Body.String() != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("expected an untagged response, got %v %q", rec.Header(), rec.Body.String())
+	}
+}
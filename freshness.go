@@ -0,0 +1,141 @@
+package headers
+
+import (
+	"net/http"
+	"time"
+)
+
+// CacheState describes whether a stored response can be reused.
+type CacheState int8
+
+const (
+	// The response is fresh and can be served without contacting the origin.
+	CacheStateFresh CacheState = iota
+	// The response is stale, but may be served while it is revalidated in the
+	// background.
+	CacheStateStaleWhileRevalidate
+	// The response is stale, but may be served if revalidating it fails.
+	CacheStateStaleIfError
+	// The response is stale and must be revalidated before it is served.
+	CacheStateStale
+)
+
+// StoredResponse describes a response held by a cache, along with the times
+// at which the cache sent the request and received the response.
+type StoredResponse struct {
+	StatusCode   int
+	Header       http.Header
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// Freshness is the result of calculating the freshness of a stored response.
+type Freshness struct {
+	// How long the response stays fresh after it was generated.
+	Lifetime time.Duration
+	// The current age of the response.
+	Age time.Duration
+	// The lifetime was estimated from Last-Modified, as the response carries
+	// no explicit expiration time.
+	Heuristic bool
+	State     CacheState
+}
+
+// Fresh reports whether the response can be reused without validation.
+func (f Freshness) Fresh() bool {
+	return f.State == CacheStateFresh
+}
+
+// The status codes which are cacheable by default, and may be assigned a
+// heuristic freshness lifetime.
+//
+// https://www.rfc-editor.org/rfc/rfc9110#section-15.1
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 206: true, 300: true, 301: true,
+	308: true, 404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// The fraction of the time since Last-Modified used as a heuristic freshness
+// lifetime, as suggested by RFC 9111.
+const heuristicFraction = 10
+
+// CalculateFreshness computes the freshness lifetime and current age of a
+// stored response at time now, following RFC 9111 section 4.2. A shared
+// cache honors s-maxage and proxy-revalidate, which private caches ignore; as
+// s-maxage implies proxy-revalidate, stale responses carrying it are never
+// served without revalidation.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-4.2
+func CalculateFreshness(res StoredResponse, now time.Time, shared bool) Freshness {
+	var cc CacheControl
+	ccErr := cc.Parse(fieldValue(res.Header, cc.Name()))
+
+	date := res.ResponseTime
+	var d Date
+	if t, err := parseHTTPDate(d.Name(), res.Header.Get(d.Name())); err == nil {
+		date = t
+	}
+
+	f := Freshness{Age: currentAge(res, date, now)}
+	switch {
+	case ccErr != nil:
+		// Directives that can't be understood leave nothing to rely on.
+	case shared && cc.SMaxAge != nil:
+		f.Lifetime = *cc.SMaxAge
+	case cc.MaxAge != nil:
+		f.Lifetime = *cc.MaxAge
+	case res.Header.Get("Expires") != "":
+		var expires Expires
+		if expires.Parse(res.Header.Get(expires.Name())); expires.Time.After(date) {
+			f.Lifetime = expires.Time.Sub(date)
+		}
+	case heuristicallyCacheable[res.StatusCode] || cc.Public:
+		var lm LastModified
+		if err := lm.Parse(res.Header.Get(lm.Name())); err == nil && lm.Time.Before(date) {
+			f.Lifetime = date.Sub(lm.Time) / heuristicFraction
+			f.Heuristic = true
+		}
+	}
+
+	staleness := f.Age - f.Lifetime
+	switch {
+	case ccErr != nil || cc.NoCache && len(cc.NoCacheFields) == 0 || pragmaNoCache(res.Header):
+		f.State = CacheStateStale
+	case staleness < 0:
+		f.State = CacheStateFresh
+	case cc.MustRevalidate || shared && (cc.ProxyRevalidate || cc.SMaxAge != nil):
+		f.State = CacheStateStale
+	case cc.StaleWhileRevalidate != nil && staleness <= *cc.StaleWhileRevalidate:
+		f.State = CacheStateStaleWhileRevalidate
+	case cc.StaleIfError != nil && staleness <= *cc.StaleIfError:
+		f.State = CacheStateStaleIfError
+	default:
+		f.State = CacheStateStale
+	}
+	return f
+}
+
+// currentAge implements the age calculation of RFC 9111 section 4.2.3.
+func currentAge(res StoredResponse, date, now time.Time) time.Duration {
+	var age Age
+	age.Parse(res.Header.Get(age.Name()))
+
+	apparentAge := res.ResponseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	correctedAge := age.Cached + res.ResponseTime.Sub(res.RequestTime)
+	initialAge := apparentAge
+	if correctedAge > initialAge {
+		initialAge = correctedAge
+	}
+	return initialAge + now.Sub(res.ResponseTime)
+}
+
+// pragmaNoCache reports whether a response carries Pragma: no-cache without
+// a Cache-Control header, which takes precedence over it.
+func pragmaNoCache(h http.Header) bool {
+	var pragma Pragma
+	pragma.Parse(h.Get(pragma.Name()))
+	return pragma.NoCache && fieldValue(h, "Cache-Control") == ""
+}
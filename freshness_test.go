@@ -0,0 +1,80 @@
+package headers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCalculateFreshness(t *testing.T) {
+	requested := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	received := requested.Add(2 * time.Second)
+	date := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	for _, c := range []struct {
+		Name      string
+		Status    int
+		Headers   map[string]string
+		Elapsed   time.Duration
+		Shared    bool
+		Lifetime  time.Duration
+		Age       time.Duration
+		Heuristic bool
+		State     CacheState
+	}{
+		{"max-age", 200, map[string]string{"Cache-Control": "max-age=60", "Date": date(received)},
+			30 * time.Second, false, time.Minute, 32 * time.Second, false, CacheStateFresh},
+		{"age header", 200, map[string]string{"Cache-Control": "max-age=60", "Date": date(received), "Age": "40"},
+			30 * time.Second, false, time.Minute, 72 * time.Second, false, CacheStateStale},
+		{"apparent age", 200, map[string]string{"Cache-Control": "max-age=60", "Date": date(requested.Add(-time.Minute))},
+			0, false, time.Minute, 62 * time.Second, false, CacheStateStale},
+		{"s-maxage shared", 200, map[string]string{"Cache-Control": "max-age=10, s-maxage=60", "Date": date(received)},
+			20 * time.Second, true, time.Minute, 22 * time.Second, false, CacheStateFresh},
+		{"s-maxage private", 200, map[string]string{"Cache-Control": "max-age=10, s-maxage=60", "Date": date(received)},
+			20 * time.Second, false, 10 * time.Second, 22 * time.Second, false, CacheStateStale},
+		{"expires", 200, map[string]string{"Expires": date(received.Add(time.Hour)), "Date": date(received)},
+			0, false, time.Hour, 2 * time.Second, false, CacheStateFresh},
+		{"invalid expires", 200, map[string]string{"Expires": "0", "Date": date(received)},
+			0, false, 0, 2 * time.Second, false, CacheStateStale},
+		{"max-age overrides expires", 200, map[string]string{"Cache-Control": "max-age=5", "Expires": date(received.Add(time.Hour))},
+			0, false, 5 * time.Second, 2 * time.Second, false, CacheStateFresh},
+		{"heuristic", 200, map[string]string{"Last-Modified": date(received.Add(-10 * time.Hour)), "Date": date(received)},
+			0, false, time.Hour, 2 * time.Second, true, CacheStateFresh},
+		{"heuristic status", 201, map[string]string{"Last-Modified": date(received.Add(-10 * time.Hour)), "Date": date(received)},
+			0, false, 0, 2 * time.Second, false, CacheStateStale},
+		{"no-cache", 200, map[string]string{"Cache-Control": "max-age=60, no-cache"},
+			0, false, time.Minute, 2 * time.Second, false, CacheStateStale},
+		{"stale-while-revalidate", 200, map[string]string{"Cache-Control": "max-age=10, stale-while-revalidate=30, stale-if-error=300"},
+			20 * time.Second, false, 10 * time.Second, 22 * time.Second, false, CacheStateStaleWhileRevalidate},
+		{"stale-if-error", 200, map[string]string{"Cache-Control": "max-age=10, stale-while-revalidate=30, stale-if-error=300"},
+			time.Minute, false, 10 * time.Second, 62 * time.Second, false, CacheStateStaleIfError},
+		{"must-revalidate", 200, map[string]string{"Cache-Control": "max-age=10, must-revalidate, stale-if-error=300"},
+			time.Minute, false, 10 * time.Second, 62 * time.Second, false, CacheStateStale},
+		{"proxy-revalidate", 200, map[string]string{"Cache-Control": "max-age=10, proxy-revalidate, stale-if-error=300"},
+			time.Minute, true, 10 * time.Second, 62 * time.Second, false, CacheStateStale},
+		{"s-maxage implies proxy-revalidate", 200, map[string]string{"Cache-Control": "s-maxage=10, stale-while-revalidate=30, stale-if-error=300"},
+			20 * time.Second, true, 10 * time.Second, 22 * time.Second, false, CacheStateStale},
+		{"pragma", 200, map[string]string{"Pragma": "no-cache", "Expires": date(received.Add(time.Hour)), "Date": date(received)},
+			0, false, time.Hour, 2 * time.Second, false, CacheStateStale},
+		{"pragma with cache-control", 200, map[string]string{"Pragma": "no-cache", "Cache-Control": "max-age=60"},
+			0, false, time.Minute, 2 * time.Second, false, CacheStateFresh},
+		{"invalid cache-control", 200, map[string]string{"Cache-Control": "max-age=10, max-age=20"},
+			0, false, 0, 2 * time.Second, false, CacheStateStale},
+	} {
+		t.Run(c.Name, func(t *testing.T) {
+			res := StoredResponse{
+				StatusCode:   c.Status,
+				Header:       http.Header{},
+				RequestTime:  requested,
+				ResponseTime: received,
+			}
+			for k, v := range c.Headers {
+				res.Header.Set(k, v)
+			}
+			f := CalculateFreshness(res, received.Add(c.Elapsed), c.Shared)
+			if f.Lifetime != c.Lifetime || f.Age != c.Age || f.Heuristic != c.Heuristic || f.State != c.State {
+				t.Errorf("expected lifetime %s, age %s, heuristic %v, state %d; got %+v", c.Lifetime, c.Age, c.Heuristic, c.State, f)
+			}
+		})
+	}
+}
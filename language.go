@@ -0,0 +1,220 @@
+package headers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validLanguageTag reports whether a string has the shape of a language
+// tag or language range: alphanumeric subtags of up to eight characters
+// separated by hyphens, the first purely alphabetic.
+//
+// https://www.rfc-editor.org/rfc/rfc4647#section-2.1
+func validLanguageTag(tag string) bool {
+	for i, subtag := range strings.Split(tag, "-") {
+		if len(subtag) == 0 || len(subtag) > 8 {
+			return false
+		}
+		for _, c := range subtag {
+			alpha := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			if !alpha && (i == 0 || c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// A LanguageRange is a member of an Accept-Language header: a language tag
+// prefix, such as "en" or "de-CH", or "*", and a weight.
+type LanguageRange struct {
+	Tag string
+	// The relative preference for the range, between 0 and 1. A weight of 0
+	// means "not acceptable".
+	Q float64
+}
+
+func (lr LanguageRange) String() string {
+	return formatWeighted(lr.Tag, nil, lr.Q)
+}
+
+// matches reports whether the range includes a language tag under basic
+// filtering: the range equals the tag, or is a prefix of it ending at a
+// subtag boundary.
+//
+// https://www.rfc-editor.org/rfc/rfc4647#section-3.3.1
+func (lr LanguageRange) matches(tag string) bool {
+	if lr.Tag == "*" {
+		return true
+	}
+	if len(tag) < len(lr.Tag) || !strings.EqualFold(tag[:len(lr.Tag)], lr.Tag) {
+		return false
+	}
+	return len(tag) == len(lr.Tag) || tag[len(lr.Tag)] == '-'
+}
+
+// The Accept-Language request HTTP header advertises which languages the
+// client is able to understand, and which locale variant is preferred.
+//
+// https://mdn.io/Accept-Language
+type AcceptLanguage struct {
+	Ranges []LanguageRange
+}
+
+func (h AcceptLanguage) Name() string {
+	return "Accept-Language"
+}
+
+func (h AcceptLanguage) Value() string {
+	v := make([]string, len(h.Ranges))
+	for i, lr := range h.Ranges {
+		v[i] = lr.String()
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *AcceptLanguage) Parse(hdr string) error {
+	list, err := parseWeightedList(h.Name(), hdr)
+	if err != nil {
+		return err
+	}
+	val := AcceptLanguage{}
+	for _, w := range list {
+		if w.value != "*" && !validLanguageTag(w.value) {
+			return fmt.Errorf("Invalid language range in Accept-Language: %s", w.value)
+		}
+		val.Ranges = append(val.Ranges, LanguageRange{Tag: w.value, Q: w.q})
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &AcceptLanguage{}
+
+// preferred returns the ranges with a non-zero weight, most preferred first.
+func (h AcceptLanguage) preferred() []LanguageRange {
+	var ranges []LanguageRange
+	for _, lr := range h.Ranges {
+		if lr.Q > 0 {
+			ranges = append(ranges, lr)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Q > ranges[j].Q
+	})
+	return ranges
+}
+
+// Quality returns the weight the header gives to a language tag, taken from
+// the longest matching range. The result is 0 if the tag is not acceptable.
+func (h AcceptLanguage) Quality(tag string) float64 {
+	best, q := -1, 0.0
+	for _, lr := range h.Ranges {
+		length := len(lr.Tag)
+		if lr.Tag == "*" {
+			length = 0
+		}
+		if length > best && lr.matches(tag) {
+			best, q = length, lr.Q
+		}
+	}
+	return q
+}
+
+// Filter returns the available language tags which the header accepts, by
+// RFC 4647 basic filtering, ordered by preference. Tags with the same weight
+// keep their order in available.
+//
+// https://www.rfc-editor.org/rfc/rfc4647#section-3.3.1
+func (h AcceptLanguage) Filter(available []string) []string {
+	var tags []string
+	var weights []float64
+	for _, tag := range available {
+		if q := h.Quality(tag); q > 0 {
+			tags = append(tags, tag)
+			weights = append(weights, q)
+		}
+	}
+	sort.Stable(byWeight{tags, weights})
+	return tags
+}
+
+type byWeight struct {
+	tags    []string
+	weights []float64
+}
+
+func (b byWeight) Len() int           { return len(b.tags) }
+func (b byWeight) Less(i, j int) bool { return b.weights[i] > b.weights[j] }
+func (b byWeight) Swap(i, j int) {
+	b.tags[i], b.tags[j] = b.tags[j], b.tags[i]
+	b.weights[i], b.weights[j] = b.weights[j], b.weights[i]
+}
+
+// Lookup returns the single available language tag which best matches the
+// header, by RFC 4647 lookup: each range, most preferred first, is compared
+// with the available tags and progressively truncated until one matches.
+// The "*" range is skipped, and def is returned if nothing matches.
+//
+// https://www.rfc-editor.org/rfc/rfc4647#section-3.4
+func (h AcceptLanguage) Lookup(available []string, def string) string {
+	for _, lr := range h.preferred() {
+		if lr.Tag == "*" {
+			continue
+		}
+		for tag := lr.Tag; tag != ""; tag = truncateLanguageTag(tag) {
+			for _, a := range available {
+				if strings.EqualFold(a, tag) {
+					return a
+				}
+			}
+		}
+	}
+	return def
+}
+
+// truncateLanguageTag removes the last subtag from a language tag, along
+// with any singleton subtag that would be left at the end.
+func truncateLanguageTag(tag string) string {
+	i := strings.LastIndexByte(tag, '-')
+	if i < 0 {
+		return ""
+	}
+	tag = tag[:i]
+	if i = strings.LastIndexByte(tag, '-'); i >= 0 && i == len(tag)-2 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// The Content-Language entity header is used to describe the language(s)
+// intended for the audience, so that it allows a user to differentiate
+// according to the users' own preferred language.
+//
+// https://mdn.io/Content-Language
+type ContentLanguage struct {
+	Tags []string
+}
+
+func (h ContentLanguage) Name() string {
+	return "Content-Language"
+}
+
+func (h ContentLanguage) Value() string {
+	return strings.Join(h.Tags, ", ")
+}
+
+func (h *ContentLanguage) Parse(hdr string) error {
+	val := ContentLanguage{}
+	for _, tag := range splitList(hdr) {
+		if !validLanguageTag(tag) {
+			return fmt.Errorf("Invalid language tag in Content-Language: %s", tag)
+		}
+		val.Tags = append(val.Tags, tag)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentLanguage{}
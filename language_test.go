@@ -0,0 +1,106 @@
+package headers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	verify(t, []testcase{
+		{&AcceptLanguage{Ranges: []LanguageRange{{Tag: "de", Q: 1}}}, "de"},
+		{&AcceptLanguage{Ranges: []LanguageRange{
+			{Tag: "fr-CH", Q: 1},
+			{Tag: "fr", Q: 0.9},
+			{Tag: "en", Q: 0.8},
+			{Tag: "*", Q: 0.5},
+		}}, "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5"},
+		{&AcceptLanguage{Ranges: []LanguageRange{{Tag: "zh-Hant-TW", Q: 1}, {Tag: "en-US", Q: 0}}}, "zh-Hant-TW, en-US;q=0"},
+	})
+
+	var h AcceptLanguage
+	for _, hdr := range []string{"en_US", "1en", "toolonglanguage", "en-", "en;q=2", "*-US"} {
+		if err := h.Parse(hdr); err == nil {
+			t.Errorf("expected error parsing %q", hdr)
+		}
+	}
+}
+
+func TestAcceptLanguageFilter(t *testing.T) {
+	available := []string{"en", "en-US", "en-GB", "de-DE", "de-CH", "fr", "es-419"}
+	for hdr, expected := range map[string][]string{
+		"de":                       {"de-DE", "de-CH"},
+		"de-ch, en;q=0.5":          {"de-CH", "en", "en-US", "en-GB"},
+		"en, en-GB;q=0":            {"en", "en-US"},
+		"*;q=0.1, fr":              {"fr", "en", "en-US", "en-GB", "de-DE", "de-CH", "es-419"},
+		"de-D":                     nil,
+		"es-419, es;q=0.5, pt":     {"es-419"},
+		"en-us;q=0.3, en-gb;q=0.6": {"en-GB", "en-US"},
+	} {
+		var h AcceptLanguage
+		if err := h.Parse(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if got := h.Filter(available); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", hdr, expected, got)
+		}
+	}
+}
+
+func TestAcceptLanguageLookup(t *testing.T) {
+	available := []string{"en", "en-GB", "de", "zh-Hant", "fr-CA"}
+	for hdr, expected := range map[string]string{
+		"":                         "en",
+		"en-GB":                    "en-GB",
+		"en-US":                    "en",
+		"de-CH-1996":               "de",
+		"zh-Hant-CN-x-private1":    "zh-Hant",
+		"fr":                       "en",
+		"fr, de;q=0.5":             "de",
+		"it, *":                    "en",
+		"de;q=0, fr-CA;q=0.2":      "fr-CA",
+		"EN-gb":                    "en-GB",
+		"es-x-foo, de-a-ext;q=0.9": "de",
+	} {
+		var h AcceptLanguage
+		if err := h.Parse(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if got := h.Lookup(available, "en"); got != expected {
+			t.Errorf("%q: expected %q, got %q", hdr, expected, got)
+		}
+	}
+}
+
+func TestContentLanguage(t *testing.T) {
+	verify(t, []testcase{
+		{&ContentLanguage{Tags: []string{"de-DE"}}, "de-DE"},
+		{&ContentLanguage{Tags: []string{"de-DE", "en-CA"}}, "de-DE, en-CA"},
+	})
+
+	var h ContentLanguage
+	if err := h.Parse("en, *"); err == nil {
+		t.Errorf("expected error for wildcard Content-Language")
+	}
+}
+
+func TestNegotiatorLanguage(t *testing.T) {
+	n := Negotiator{ContentTypes: []string{"text/html"}, Languages: []string{"en", "de", "fr-CA"}}
+	for hdr, expected := range map[string]string{
+		"":             "en",
+		"de-AT, en":    "de",
+		"fr-CA-x-a":    "fr-CA",
+		"ja":           "en",
+		"not valid!!!": "en",
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", hdr)
+		result, status := n.Negotiate(r)
+		if result.Language != expected || status != 200 {
+			t.Errorf("%q: expected %q, got %q %d", hdr, expected, result.Language, status)
+		}
+		if !reflect.DeepEqual(result.Vary, []string{"Accept", "Accept-Language"}) {
+			t.Errorf("unexpected Vary %v", result.Vary)
+		}
+	}
+}
@@ -9,26 +9,45 @@ type parser struct {
 	index  int
 	chars  []rune
 	r      rune
+	sep    rune
 	err    error
 	k, v   strings.Builder
-	output map[string]string
+	output []directive
 	eof    bool
 }
 
+// A directive is a single name and optional value, in the order it appeared
+// in the header.
+type directive struct {
+	name, value string
+}
+
 // XXX: Add a specific error message type
 func ParseDirectives(input string) (map[string]string, error) {
+	list, err := parseDirectiveList(input, ';')
+	output := map[string]string{}
+	for _, d := range list {
+		output[d.name] = d.value
+	}
+	return output, err
+}
+
+// parseDirectiveList parses directives separated by sep, keeping duplicates
+// and their original order. Cache-Control style headers use ',' while most
+// security headers use ';'.
+func parseDirectiveList(input string, sep rune) ([]directive, error) {
 	if input == "" {
-		return map[string]string{}, nil
+		return nil, nil
 	}
-	return newParser(input).parse()
+	return newParser(input, sep).parse()
 }
 
-func newParser(input string) *parser {
+func newParser(input string, sep rune) *parser {
 	var chars []rune
 	for _, c := range input {
 		chars = append(chars, c)
 	}
-	return &parser{chars: chars, r: chars[0], output: map[string]string{}}
+	return &parser{chars: chars, r: chars[0], sep: sep}
 }
 
 func (p *parser) accept(r rune) bool {
@@ -64,7 +83,7 @@ func (p *parser) expect(s rune) bool {
 	return false
 }
 
-func (p *parser) parse() (map[string]string, error) {
+func (p *parser) parse() ([]directive, error) {
 	if p.r == 0 {
 		return p.output, nil
 	}
@@ -72,7 +91,7 @@ func (p *parser) parse() (map[string]string, error) {
 		if p.directive(); p.done() {
 			return p.output, p.err
 		}
-		if !p.expect(';') {
+		if !p.expect(p.sep) {
 			return p.output, p.err
 		}
 	}
@@ -85,7 +104,7 @@ func (p *parser) directive() {
 		p.value()
 	}
 	if p.k.Len() > 0 {
-		p.output[p.k.String()] = p.v.String()
+		p.output = append(p.output, directive{p.k.String(), p.v.String()})
 		p.k.Reset()
 		p.v.Reset()
 	}
@@ -105,7 +124,7 @@ func (p *parser) lws() {
 func (p *parser) name() {
 	p.k.Reset()
 	for {
-		if p.eof {
+		if p.eof || p.r == p.sep {
 			return
 		}
 		switch p.r {
@@ -139,7 +158,7 @@ func (p *parser) value() {
 			}
 			p.accept('\\')
 		} else {
-			if p.eof {
+			if p.eof || p.r == p.sep {
 				return
 			}
 			switch p.r {
@@ -158,3 +177,34 @@ func (p *parser) value() {
 		}
 	}
 }
+
+// splitList splits a comma-separated header list into its members, trimming
+// optional whitespace and dropping empty elements. Commas inside quoted
+// strings do not split the list.
+func splitList(input string) []string {
+	var members []string
+	var b strings.Builder
+	quoted, escaped := false, false
+	flush := func() {
+		if m := strings.TrimSpace(b.String()); m != "" {
+			members = append(members, m)
+		}
+		b.Reset()
+	}
+	for _, c := range input {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			flush()
+			continue
+		}
+		b.WriteRune(c)
+	}
+	flush()
+	return members
+}
@@ -38,3 +38,22 @@ func TestInvalid(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	for input, expected := range map[string][]string{
+		"":             nil,
+		" a , b,,c ":   {"a", "b", "c"},
+		`"a,b", W/"c"`: {`"a,b"`, `W/"c"`},
+		`"a\",b", c`:   {`"a\",b"`, "c"},
+	} {
+		actual := splitList(input)
+		if len(actual) != len(expected) {
+			t.Fatalf("%q: expected %q, got %q", input, expected, actual)
+		}
+		for i := range actual {
+			if actual[i] != expected[i] {
+				t.Errorf("%q: expected %q, got %q", input, expected, actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package headers
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ByteRange is a range of bytes requested in a Range header. Both
+// positions are inclusive.
+type ByteRange struct {
+	// The first byte of the range. If negative, the range is a suffix range
+	// holding the last End bytes of the representation.
+	Start int64
+	// The last byte of the range. If negative, the range extends to the end
+	// of the representation.
+	End int64
+}
+
+func (br ByteRange) String() string {
+	switch {
+	case br.Start < 0:
+		return "-" + strconv.FormatInt(br.End, 10)
+	case br.End < 0:
+		return strconv.FormatInt(br.Start, 10) + "-"
+	}
+	return strconv.FormatInt(br.Start, 10) + "-" + strconv.FormatInt(br.End, 10)
+}
+
+// Resolve returns the offset and length of the range within a representation
+// of the given size. The result is false if the range is unsatisfiable.
+func (br ByteRange) Resolve(size int64) (int64, int64, bool) {
+	switch {
+	case br.Start < 0:
+		if br.End == 0 || size == 0 {
+			return 0, 0, false
+		}
+		if br.End > size {
+			return 0, size, true
+		}
+		return size - br.End, br.End, true
+	case br.Start >= size:
+		return 0, 0, false
+	case br.End < 0 || br.End >= size:
+		return br.Start, size - br.Start, true
+	}
+	return br.Start, br.End - br.Start + 1, true
+}
+
+// The Range HTTP request header indicates the part of a document that the
+// server should return. Several parts can be requested with one Range header
+// at once, and the server may send back these ranges in a multipart document.
+//
+// https://mdn.io/Range
+type Range struct {
+	// The unit in which ranges are specified. This is usually bytes.
+	Unit   string
+	Ranges []ByteRange
+}
+
+func (h Range) Name() string {
+	return "Range"
+}
+
+func (h Range) Value() string {
+	unit := h.Unit
+	if unit == "" {
+		unit = "bytes"
+	}
+	v := make([]string, len(h.Ranges))
+	for i, br := range h.Ranges {
+		v[i] = br.String()
+	}
+	return unit + "=" + strings.Join(v, ", ")
+}
+
+func (h *Range) Parse(hdr string) error {
+	eq := strings.IndexByte(hdr, '=')
+	if eq <= 0 {
+		return fmt.Errorf("Range must start with a unit; got %s", hdr)
+	}
+	val := Range{Unit: strings.ToLower(strings.TrimSpace(hdr[:eq]))}
+	for _, spec := range splitList(hdr[eq+1:]) {
+		dash := strings.IndexByte(spec, '-')
+		if dash < 0 {
+			return fmt.Errorf("Invalid range %s in %s", spec, hdr)
+		}
+		first, last := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+		br := ByteRange{Start: -1, End: -1}
+		var err error
+		if first != "" {
+			if br.Start, err = parsePosition(first); err != nil {
+				return fmt.Errorf("Invalid range %s in %s", spec, hdr)
+			}
+		}
+		if last != "" {
+			if br.End, err = parsePosition(last); err != nil {
+				return fmt.Errorf("Invalid range %s in %s", spec, hdr)
+			}
+		}
+		if first == "" && last == "" || first != "" && last != "" && br.End < br.Start {
+			return fmt.Errorf("Invalid range %s in %s", spec, hdr)
+		}
+		val.Ranges = append(val.Ranges, br)
+	}
+	if len(val.Ranges) == 0 {
+		return fmt.Errorf("Range must hold at least one range; got %s", hdr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &Range{}
+
+func parsePosition(s string) (int64, error) {
+	if strings.Trim(s, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid position %s", s)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// The Content-Range response HTTP header indicates where in a full body
+// message a partial message belongs.
+//
+// https://mdn.io/Content-Range
+type ContentRange struct {
+	// The unit in which ranges are specified. This is usually bytes.
+	Unit string
+	// The first and last byte of the enclosed range, inclusive.
+	Start, End int64
+	// The total length of the representation, or -1 if unknown.
+	Size int64
+	// The requested ranges could not be satisfied, and the header only holds
+	// the size of the representation.
+	Unsatisfied bool
+}
+
+func (h ContentRange) Name() string {
+	return "Content-Range"
+}
+
+func (h ContentRange) Value() string {
+	unit := h.Unit
+	if unit == "" {
+		unit = "bytes"
+	}
+	size := "*"
+	if h.Size >= 0 {
+		size = strconv.FormatInt(h.Size, 10)
+	}
+	if h.Unsatisfied {
+		return unit + " */" + size
+	}
+	return unit + " " + strconv.FormatInt(h.Start, 10) + "-" + strconv.FormatInt(h.End, 10) + "/" + size
+}
+
+func (h *ContentRange) Parse(hdr string) error {
+	sp := strings.IndexByte(hdr, ' ')
+	slash := strings.LastIndexByte(hdr, '/')
+	if sp <= 0 || slash < sp {
+		return fmt.Errorf("Invalid Content-Range; got %s", hdr)
+	}
+	val := ContentRange{Unit: hdr[:sp], Size: -1}
+	var err error
+	if size := hdr[slash+1:]; size != "*" {
+		if val.Size, err = parsePosition(size); err != nil {
+			return fmt.Errorf("Invalid Content-Range length; got %s", hdr)
+		}
+	}
+	rng := hdr[sp+1 : slash]
+	if rng == "*" {
+		if val.Size < 0 {
+			return fmt.Errorf("Unsatisfied Content-Range must hold a length; got %s", hdr)
+		}
+		val.Unsatisfied = true
+		*h = val
+		return nil
+	}
+	dash := strings.IndexByte(rng, '-')
+	if dash < 0 {
+		return fmt.Errorf("Invalid Content-Range; got %s", hdr)
+	}
+	if val.Start, err = parsePosition(rng[:dash]); err != nil {
+		return fmt.Errorf("Invalid Content-Range; got %s", hdr)
+	}
+	if val.End, err = parsePosition(rng[dash+1:]); err != nil {
+		return fmt.Errorf("Invalid Content-Range; got %s", hdr)
+	}
+	if val.End < val.Start || val.Size >= 0 && val.End >= val.Size {
+		return fmt.Errorf("Invalid Content-Range; got %s", hdr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ContentRange{}
+
+// The Accept-Ranges HTTP response header is a marker used by the server to
+// advertise its support for partial requests from the client for file
+// downloads.
+//
+// https://mdn.io/Accept-Ranges
+type AcceptRanges struct {
+	// The range units supported by the server. If empty, the server does not
+	// support range requests, and the header is sent as "none".
+	Units []string
+}
+
+func (h AcceptRanges) Name() string {
+	return "Accept-Ranges"
+}
+
+func (h AcceptRanges) Value() string {
+	if len(h.Units) == 0 {
+		return "none"
+	}
+	return strings.Join(h.Units, ", ")
+}
+
+func (h *AcceptRanges) Parse(hdr string) error {
+	val := AcceptRanges{}
+	for _, unit := range splitList(hdr) {
+		if !strings.EqualFold(unit, "none") {
+			val.Units = append(val.Units, strings.ToLower(unit))
+		}
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &AcceptRanges{}
+
+// The If-Range HTTP request header makes a range request conditional: if the
+// condition is fulfilled, the range request is issued, and the server sends
+// back a 206 Partial Content answer with the appropriate body. If the
+// condition is not fulfilled, the full resource is sent back with a 200 OK
+// status.
+//
+// https://mdn.io/If-Range
+type IfRange struct {
+	// The entity-tag of the representation the client holds. If nil, Time is
+	// used instead.
+	ETag *ETag
+	// The last modification time of the representation the client holds.
+	Time time.Time
+}
+
+func (h IfRange) Name() string {
+	return "If-Range"
+}
+
+func (h IfRange) Value() string {
+	if h.ETag != nil {
+		return h.ETag.Value()
+	}
+	return formatHTTPDate(h.Time)
+}
+
+func (h *IfRange) Parse(hdr string) error {
+	var tag ETag
+	if err := tag.Parse(hdr); err == nil {
+		*h = IfRange{ETag: &tag}
+		return nil
+	}
+	t, err := parseHTTPDate(h.Name(), hdr)
+	if err != nil {
+		return fmt.Errorf("If-Range must hold an entity-tag or a date; got %s", hdr)
+	}
+	*h = IfRange{Time: t}
+	return nil
+}
+
+var _ Header = &IfRange{}
+
+// Matches reports whether the representation the client holds is the current
+// one. Entity-tags must match strongly, and dates must exactly match the
+// current last modification time.
+func (h IfRange) Matches(etag *ETag, modified time.Time) bool {
+	if h.ETag != nil {
+		return etag != nil && h.ETag.StrongMatch(*etag)
+	}
+	return !modified.IsZero() && modified.Truncate(time.Second).Equal(h.Time)
+}
+
+// RangeOptions configures how ServeRanges answers range requests.
+type RangeOptions struct {
+	// The maximum number of ranges served in a single response, after
+	// overlapping ranges have been merged. Requests for more ranges receive
+	// the full representation. If zero, DefaultMaxRanges is used.
+	MaxRanges int
+}
+
+// The number of ranges ServeRanges serves in one response by default.
+const DefaultMaxRanges = 16
+
+// ServeRanges replies to a request with the contents of a representation,
+// honoring Range and If-Range. A single range is sent as a 206 (Partial
+// Content) response, several as a multipart/byteranges document, and an
+// unsatisfiable Range header results in a 416 (Range Not Satisfiable)
+// response with a Content-Range header.
+//
+// Overlapping and adjacent ranges are merged, and requests for more than
+// MaxRanges ranges, or for more bytes than the representation holds, are
+// answered with the full representation.
+//
+// The ETag, Last-Modified and Content-Type headers of the response should be
+// set before calling ServeRanges: they are used to evaluate If-Range and to
+// describe each part of a multipart response.
+func ServeRanges(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, opts RangeOptions) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		http.Error(w, "Seeker can't seek", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Accept-Ranges", AcceptRanges{[]string{"bytes"}}.Value())
+
+	ranges, ok := requestedRanges(w, r, size, opts)
+	if !ok {
+		cr := ContentRange{Size: size, Unsatisfied: true}
+		w.Header().Set(cr.Name(), cr.Value())
+		http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	switch len(ranges) {
+	case 0:
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			copyRange(w, content, 0, size)
+		}
+	case 1:
+		cr := ContentRange{Start: ranges[0][0], End: ranges[0][0] + ranges[0][1] - 1, Size: size}
+		w.Header().Set(cr.Name(), cr.Value())
+		w.Header().Set("Content-Length", strconv.FormatInt(ranges[0][1], 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if r.Method != http.MethodHead {
+			copyRange(w, content, ranges[0][0], ranges[0][1])
+		}
+	default:
+		contentType := w.Header().Get("Content-Type")
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusPartialContent)
+		if r.Method == http.MethodHead {
+			return
+		}
+		for _, rng := range ranges {
+			cr := ContentRange{Start: rng[0], End: rng[0] + rng[1] - 1, Size: size}
+			part := textproto.MIMEHeader{cr.Name(): {cr.Value()}}
+			if contentType != "" {
+				part.Set("Content-Type", contentType)
+			}
+			pw, err := mw.CreatePart(part)
+			if err != nil || copyRange(pw, content, rng[0], rng[1]) != nil {
+				return
+			}
+		}
+		mw.Close()
+	}
+}
+
+// requestedRanges returns the offset and length of the ranges to serve, or
+// none if the full representation should be sent. The result is false if
+// none of the requested ranges can be satisfied.
+func requestedRanges(w http.ResponseWriter, r *http.Request, size int64, opts RangeOptions) ([][2]int64, bool) {
+	hdr := r.Header.Get("Range")
+	if hdr == "" || r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil, true
+	}
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" {
+		var ir IfRange
+		var etag *ETag
+		if tag := (ETag{}); tag.Parse(w.Header().Get(tag.Name())) == nil {
+			etag = &tag
+		}
+		var lm LastModified
+		lm.Parse(w.Header().Get(lm.Name()))
+		if ir.Parse(ifRange) != nil || !ir.Matches(etag, lm.Time) {
+			return nil, true
+		}
+	}
+
+	// Invalid Range headers and unknown units are ignored.
+	var rng Range
+	if rng.Parse(hdr) != nil || rng.Unit != "bytes" {
+		return nil, true
+	}
+	var ranges [][2]int64
+	var requested int64
+	for _, br := range rng.Ranges {
+		if start, length, ok := br.Resolve(size); ok {
+			ranges = append(ranges, [2]int64{start, length})
+			requested += length
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, false
+	}
+	if requested > size {
+		return nil, true
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next[0] <= last[0]+last[1] {
+			if end := next[0] + next[1]; end > last[0]+last[1] {
+				last[1] = end - last[0]
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+
+	limit := opts.MaxRanges
+	if limit == 0 {
+		limit = DefaultMaxRanges
+	}
+	if len(merged) > limit || len(merged) == 1 && merged[0][1] == size {
+		return nil, true
+	}
+	return merged, true
+}
+
+func copyRange(w io.Writer, content io.ReadSeeker, start, length int64) error {
+	if _, err := content.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, content, length)
+	return err
+}
@@ -0,0 +1,161 @@
+package headers
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRangeHeaders(t *testing.T) {
+	modified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	verify(t, []testcase{
+		{&Range{Unit: "bytes", Ranges: []ByteRange{{0, 499}}}, "bytes=0-499"},
+		{&Range{Unit: "bytes", Ranges: []ByteRange{{200, 999}, {-1, 500}, {9500, -1}}}, "bytes=200-999, -500, 9500-"},
+		{&ContentRange{Unit: "bytes", Start: 200, End: 1000, Size: 67589}, "bytes 200-1000/67589"},
+		{&ContentRange{Unit: "bytes", Start: 42, End: 1233, Size: -1}, "bytes 42-1233/*"},
+		{&ContentRange{Unit: "bytes", Size: 67589, Unsatisfied: true}, "bytes */67589"},
+		{&AcceptRanges{}, "none"},
+		{&AcceptRanges{[]string{"bytes"}}, "bytes"},
+		{&IfRange{ETag: &ETag{Tag: "v1"}}, "\"v1\""},
+		{&IfRange{Time: modified}, "Wed, 21 Oct 2015 07:28:00 GMT"},
+	})
+}
+
+func TestRangeInvalid(t *testing.T) {
+	for _, hdr := range []string{"0-1", "bytes=", "bytes=-", "bytes=5-1", "bytes=a-b", "bytes=1", "bytes=-1-2"} {
+		var rng Range
+		if err := rng.Parse(hdr); err == nil {
+			t.Errorf("Range %q: expected err", hdr)
+		}
+	}
+	for _, hdr := range []string{"bytes", "bytes 1-2", "bytes */*", "bytes 5-1/10", "bytes 0-10/10"} {
+		var cr ContentRange
+		if err := cr.Parse(hdr); err == nil {
+			t.Errorf("Content-Range %q: expected err", hdr)
+		}
+	}
+}
+
+func TestByteRangeResolve(t *testing.T) {
+	for _, c := range []struct {
+		Range         ByteRange
+		Start, Length int64
+		Satisfiable   bool
+	}{
+		{ByteRange{0, 9}, 0, 10, true},
+		{ByteRange{0, 500}, 0, 100, true},
+		{ByteRange{50, -1}, 50, 50, true},
+		{ByteRange{100, -1}, 0, 0, false},
+		{ByteRange{-1, 10}, 90, 10, true},
+		{ByteRange{-1, 500}, 0, 100, true},
+		{ByteRange{-1, 0}, 0, 0, false},
+	} {
+		start, length, ok := c.Range.Resolve(100)
+		if start != c.Start || length != c.Length || ok != c.Satisfiable {
+			t.Errorf("%s: expected %d, %d, %v; got %d, %d, %v", c.Range, c.Start, c.Length, c.Satisfiable, start, length, ok)
+		}
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	modified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	etag := &ETag{Tag: "v1"}
+	for _, c := range []struct {
+		IfRange  IfRange
+		Expected bool
+	}{
+		{IfRange{ETag: &ETag{Tag: "v1"}}, true},
+		{IfRange{ETag: &ETag{Tag: "v1", Weak: true}}, false},
+		{IfRange{ETag: &ETag{Tag: "v2"}}, false},
+		{IfRange{Time: modified}, true},
+		{IfRange{Time: modified.Add(-time.Second)}, false},
+	} {
+		if c.IfRange.Matches(etag, modified.Add(time.Millisecond)) != c.Expected {
+			t.Errorf("%s: expected %v", c.IfRange.Value(), c.Expected)
+		}
+	}
+}
+
+func serveRanges(headers map[string]string, opts RangeOptions) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/", nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("ETag", `"v1"`)
+	ServeRanges(w, r, strings.NewReader("0123456789abcdefghij"), opts)
+	return w
+}
+
+func TestServeRanges(t *testing.T) {
+	for _, c := range []struct {
+		Headers      map[string]string
+		Status       int
+		ContentRange string
+		Body         string
+	}{
+		{nil, http.StatusOK, "", "0123456789abcdefghij"},
+		{map[string]string{"Range": "bytes=0-4"}, http.StatusPartialContent, "bytes 0-4/20", "01234"},
+		{map[string]string{"Range": "bytes=-5"}, http.StatusPartialContent, "bytes 15-19/20", "fghij"},
+		{map[string]string{"Range": "bytes=15-"}, http.StatusPartialContent, "bytes 15-19/20", "fghij"},
+		{map[string]string{"Range": "bytes=0-4, 3-7"}, http.StatusPartialContent, "bytes 0-7/20", "01234567"},
+		{map[string]string{"Range": "bytes=0-"}, http.StatusOK, "", "0123456789abcdefghij"},
+		{map[string]string{"Range": "bytes=20-"}, http.StatusRequestedRangeNotSatisfiable, "bytes */20", ""},
+		{map[string]string{"Range": "bytes=oops"}, http.StatusOK, "", "0123456789abcdefghij"},
+		{map[string]string{"Range": "lines=0-1"}, http.StatusOK, "", "0123456789abcdefghij"},
+		{map[string]string{"Range": "bytes=0-4", "If-Range": `"v1"`}, http.StatusPartialContent, "bytes 0-4/20", "01234"},
+		{map[string]string{"Range": "bytes=0-4", "If-Range": `"v0"`}, http.StatusOK, "", "0123456789abcdefghij"},
+		{map[string]string{"Range": "bytes=0-15, 5-19"}, http.StatusOK, "", "0123456789abcdefghij"},
+	} {
+		w := serveRanges(c.Headers, RangeOptions{})
+		if w.Code != c.Status || w.Header().Get("Content-Range") != c.ContentRange {
+			t.Errorf("%v: expected %d %q, got %d %q", c.Headers, c.Status, c.ContentRange, w.Code, w.Header().Get("Content-Range"))
+		}
+		if c.Body != "" && w.Body.String() != c.Body {
+			t.Errorf("%v: expected body %q, got %q", c.Headers, c.Body, w.Body.String())
+		}
+		if w.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("%v: expected Accept-Ranges", c.Headers)
+		}
+	}
+}
+
+func TestServeRangesMultipart(t *testing.T) {
+	w := serveRanges(map[string]string{"Range": "bytes=10-11, 0-1, -2"}, RangeOptions{})
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", w.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for _, expected := range []struct{ ContentRange, Body string }{
+		{"bytes 0-1/20", "01"},
+		{"bytes 10-11/20", "ab"},
+		{"bytes 18-19/20", "ij"},
+	} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(part)
+		if part.Header.Get("Content-Range") != expected.ContentRange || part.Header.Get("Content-Type") != "text/plain" || string(body) != expected.Body {
+			t.Errorf("unexpected part %v %q", part.Header, body)
+		}
+	}
+	if _, err := mr.NextPart(); err == nil {
+		t.Errorf("expected 3 parts")
+	}
+
+	w = serveRanges(map[string]string{"Range": "bytes=0-0, 2-2, 4-4"}, RangeOptions{MaxRanges: 2})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected too many ranges to be ignored, got %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package headers
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -230,3 +231,140 @@ func (h *ContentTypeOptions) Parse(hdr string) error {
 }
 
 var _ Header = &ContentTypeOptions{}
+
+// A ResourcePolicy says who may load a resource with
+// Cross-Origin-Resource-Policy.
+type ResourcePolicy int
+
+const (
+	// Only requests from the same origin can load the resource.
+	ResourcePolicySameOrigin ResourcePolicy = iota
+	// Only requests from the same site can load the resource.
+	ResourcePolicySameSite
+	// Requests from any origin can load the resource.
+	ResourcePolicyCrossOrigin
+)
+
+// The HTTP Cross-Origin-Resource-Policy response header conveys a desire that
+// the browser blocks no-cors cross-origin/cross-site requests to the given
+// resource.
+//
+// https://mdn.io/Cross-Origin-Resource-Policy
+type CrossOriginResourcePolicy struct {
+	Policy ResourcePolicy
+}
+
+func (h CrossOriginResourcePolicy) Name() string {
+	return "Cross-Origin-Resource-Policy"
+}
+
+func (h CrossOriginResourcePolicy) Value() string {
+	switch h.Policy {
+	case ResourcePolicySameSite:
+		return "same-site"
+	case ResourcePolicyCrossOrigin:
+		return "cross-origin"
+	default:
+		return "same-origin"
+	}
+}
+
+func (h *CrossOriginResourcePolicy) Parse(hdr string) error {
+	val := CrossOriginResourcePolicy{}
+	switch hdr {
+	case "same-origin":
+		val.Policy = ResourcePolicySameOrigin
+	case "same-site":
+		val.Policy = ResourcePolicySameSite
+	case "cross-origin":
+		val.Policy = ResourcePolicyCrossOrigin
+	default:
+		return fmt.Errorf("Unknown Cross-Origin-Resource-Policy: %s", hdr)
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &CrossOriginResourcePolicy{}
+
+// A ClearSiteDataType is a kind of data the Clear-Site-Data header asks the
+// browser to clear.
+type ClearSiteDataType string
+
+const (
+	// Locally cached data, such as the HTTP cache and prefetched resources.
+	ClearSiteDataCache ClearSiteDataType = "cache"
+	// All cookies for the origin, along with HTTP authentication
+	// credentials.
+	ClearSiteDataCookies ClearSiteDataType = "cookies"
+	// All DOM storage for the origin, such as localStorage, IndexedDB and
+	// service worker registrations.
+	ClearSiteDataStorage ClearSiteDataType = "storage"
+	// Reloads all browsing contexts for the origin.
+	ClearSiteDataExecutionContexts ClearSiteDataType = "executionContexts"
+	// All client hint preferences stored for the origin.
+	ClearSiteDataClientHints ClearSiteDataType = "clientHints"
+	// All types of data, including those defined in the future.
+	ClearSiteDataAll ClearSiteDataType = "*"
+)
+
+// The Clear-Site-Data header clears browsing data (cookies, storage, cache)
+// associated with the requesting website. It allows web developers to have
+// more control over the data stored by a client browser for their origins.
+//
+// Browsers ignore directives that are not quoted.
+//
+// https://mdn.io/Clear-Site-Data
+type ClearSiteData struct {
+	Types []ClearSiteDataType
+}
+
+func (h ClearSiteData) Name() string {
+	return "Clear-Site-Data"
+}
+
+func (h ClearSiteData) Value() string {
+	v := make([]string, len(h.Types))
+	for i, t := range h.Types {
+		v[i] = "\"" + string(t) + "\""
+	}
+	return strings.Join(v, ", ")
+}
+
+func (h *ClearSiteData) Parse(hdr string) error {
+	val := ClearSiteData{}
+	for _, member := range splitList(hdr) {
+		if len(member) < 2 || member[0] != '"' || member[len(member)-1] != '"' {
+			return fmt.Errorf("Clear-Site-Data directives must be quoted; got %s", member)
+		}
+		val.Types = append(val.Types, ClearSiteDataType(member[1:len(member)-1]))
+	}
+	*h = val
+	return nil
+}
+
+var _ Header = &ClearSiteData{}
+
+// SignOut prepares a response which signs the user out: it asks the browser
+// to clear the site's cache, cookies and storage, and expires the given
+// cookies for browsers that don't support Clear-Site-Data. The cookies must
+// have the Name, Path and Domain used when they were set.
+func SignOut(w http.ResponseWriter, cookies ...*http.Cookie) {
+	Set(w, &ClearSiteData{[]ClearSiteDataType{
+		ClearSiteDataCache,
+		ClearSiteDataCookies,
+		ClearSiteDataStorage,
+	}})
+	for _, c := range cookies {
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.Name,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package headers
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -48,3 +50,49 @@ func TestContentTypeOptions(t *testing.T) {
 		{&nosniff, "nosniff"},
 	})
 }
+
+func TestCrossOriginResourcePolicy(t *testing.T) {
+	verify(t, []testcase{
+		{&CrossOriginResourcePolicy{}, "same-origin"},
+		{&CrossOriginResourcePolicy{Policy: ResourcePolicySameSite}, "same-site"},
+		{&CrossOriginResourcePolicy{Policy: ResourcePolicyCrossOrigin}, "cross-origin"},
+	})
+
+	var h CrossOriginResourcePolicy
+	if err := h.Parse("same-orig"); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
+
+func TestClearSiteData(t *testing.T) {
+	verify(t, []testcase{
+		{&ClearSiteData{}, ""},
+		{&ClearSiteData{[]ClearSiteDataType{ClearSiteDataAll}}, "\"*\""},
+		{&ClearSiteData{[]ClearSiteDataType{ClearSiteDataCache, ClearSiteDataCookies, ClearSiteDataStorage, ClearSiteDataExecutionContexts, ClearSiteDataClientHints}},
+			"\"cache\", \"cookies\", \"storage\", \"executionContexts\", \"clientHints\""},
+	})
+	var csd ClearSiteData
+	if err := csd.Parse("cache, \"cookies\""); err == nil {
+		t.Errorf("expected unquoted directive to be rejected")
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	w := httptest.NewRecorder()
+	SignOut(w, &http.Cookie{Name: "session", Path: "/", HttpOnly: true}, &http.Cookie{Name: "csrf", Domain: "example.com"})
+	if v := w.Header().Get("Clear-Site-Data"); v != "\"cache\", \"cookies\", \"storage\"" {
+		t.Errorf("unexpected Clear-Site-Data %q", v)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %d", len(cookies))
+	}
+	for _, c := range cookies {
+		if c.Value != "" || c.MaxAge != -1 || !c.Expires.Equal(time.Unix(0, 0)) {
+			t.Errorf("expected %s to be expired, got %s", c.Name, c.String())
+		}
+	}
+	if cookies[0].Path != "/" || !cookies[0].HttpOnly || cookies[1].Domain != "example.com" {
+		t.Errorf("expected cookie attributes to be kept, got %v", cookies)
+	}
+}
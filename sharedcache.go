@@ -0,0 +1,571 @@
+package headers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SharedCache is an http.Handler middleware implementing a shared HTTP cache,
+// such as one run in front of an internal service. It honors s-maxage,
+// private, no-store and Vary, serves stored responses with an Age header,
+// collapses concurrent misses for the same resource into a single call to the
+// wrapped handler, and refreshes responses in the background while
+// stale-while-revalidate allows serving them stale.
+//
+// https://www.rfc-editor.org/rfc/rfc9111
+type SharedCache struct {
+	// Where responses are stored. Entries evicted by a custom store are
+	// only dropped from the tag index used by Purge if the store reports
+	// them, as MemoryStore does through OnEvict.
+	Store CacheStore
+	// If set, the cache appends an entry to the Cache-Status header of each
+	// response, identifying itself by this name.
+	Name string
+	// The largest response body, in bytes, the cache stores. Larger
+	// responses are passed through to the client. If zero,
+	// DefaultMaxObjectSize is used.
+	MaxObjectSize int64
+
+	mu      sync.Mutex
+	flights map[string]*flight
+	tags    map[string]map[string]bool
+	now     func() time.Time
+}
+
+// A flight is a call to the wrapped handler which concurrent requests for the
+// same resource wait on.
+type flight struct {
+	wg     sync.WaitGroup
+	result fetchResult
+	// Set if the wrapped handler panicked, leaving no result.
+	failed bool
+}
+
+// fetchResult is the outcome of calling the wrapped handler.
+type fetchResult struct {
+	entry *CacheEntry
+	// The status code returned by the wrapped handler.
+	status int
+	stored bool
+	// Set if the response was passed through to the client as it was
+	// written, leaving no entry.
+	passed bool
+}
+
+// NewSharedCache returns a SharedCache which stores responses in memory,
+// holding up to capacity of them.
+func NewSharedCache(capacity int) *SharedCache {
+	store := NewMemoryStore(capacity)
+	c := &SharedCache{Store: store}
+	store.OnEvict = c.evicted
+	return c
+}
+
+func (c *SharedCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// Handler wraps next with the cache.
+func (c *SharedCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK, before: func(status int) {
+				c.appendStatus(w.Header(), CacheStatusEntry{Fwd: CacheFwdMethod, FwdStatus: status})
+			}}
+			next.ServeHTTP(sw, r)
+			if !sw.wroteHeader {
+				sw.WriteHeader(http.StatusOK)
+			}
+			if !safeMethod(r.Method) && sw.status < 400 {
+				c.delete(cacheKey(r))
+			}
+			return
+		}
+		c.serve(w, r, next)
+	})
+}
+
+func (c *SharedCache) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rcc := requestCacheControl(r)
+
+	entry, ok := lookupEntry(c.Store, r)
+	if !ok {
+		fwd := CacheFwdURIMiss
+		if _, ok := c.Store.Get(cacheKey(r)); ok {
+			fwd = CacheFwdVaryMiss
+		}
+		if rcc.OnlyIfCached {
+			c.appendStatus(w.Header(), CacheStatusEntry{Fwd: fwd})
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		c.serveFetched(w, r, next, nil, fwd)
+		return
+	}
+
+	f := CalculateFreshness(entry.StoredResponse, c.clock(), true)
+	var cc CacheControl
+	cc.Parse(fieldValue(entry.Header, cc.Name()))
+	switch {
+	case rcc.AcceptsShared(cc, f.Age, f.Lifetime):
+		c.serveHit(w, r, entry, f)
+	case f.State == CacheStateStaleWhileRevalidate && !rcc.NoCache:
+		c.serveHit(w, r, entry, f)
+		background := r.Clone(context.Background())
+		go c.fetch(nil, background, next, entry)
+	case rcc.OnlyIfCached:
+		c.appendStatus(w.Header(), CacheStatusEntry{Fwd: CacheFwdStale})
+		w.WriteHeader(http.StatusGatewayTimeout)
+	case f.Fresh():
+		c.serveFetched(w, r, next, entry, CacheFwdRequest)
+	default:
+		c.serveFetched(w, r, next, entry, CacheFwdStale)
+	}
+}
+
+// serveHit serves a stored response without calling the wrapped handler.
+func (c *SharedCache) serveHit(w http.ResponseWriter, r *http.Request, entry *CacheEntry, f Freshness) {
+	ttl := f.Lifetime - f.Age
+	c.appendStatus(w.Header(), CacheStatusEntry{Hit: true, TTL: &ttl})
+	serveEntry(w, r, entry, f.Age)
+}
+
+// serveFetched serves a response obtained from the wrapped handler, either
+// by this request or by a concurrent one for the same resource. A stale
+// stored response may be given for revalidation.
+func (c *SharedCache) serveFetched(w http.ResponseWriter, r *http.Request, next http.Handler, stale *CacheEntry, fwd CacheFwd) {
+	pass := &statusWriter{ResponseWriter: w, status: http.StatusOK, before: func(status int) {
+		c.appendStatus(w.Header(), CacheStatusEntry{Fwd: fwd, FwdStatus: status})
+	}}
+	res, collapsed := c.fetch(pass, r, next, stale)
+	if collapsed && (res.passed || !reusable(r, res.entry)) {
+		// The response produced for another request can't be reused for
+		// this one, so it needs its own call to the wrapped handler. The
+		// call isn't coalesced: concurrent waiters would most likely get an
+		// unusable response too, and would otherwise queue up one flight
+		// after another.
+		res, collapsed = c.upstream(pass, r, next, stale), false
+	}
+	if res.passed {
+		return
+	}
+	f := CalculateFreshness(res.entry.StoredResponse, c.clock(), true)
+	status := CacheStatusEntry{Fwd: fwd, FwdStatus: res.status, Stored: res.stored, Collapsed: collapsed}
+	if res.stored {
+		ttl := f.Lifetime - f.Age
+		status.TTL = &ttl
+	}
+	c.appendStatus(w.Header(), status)
+	serveEntry(w, r, res.entry, f.Age)
+}
+
+// appendStatus adds the cache's entry to a Cache-Status header, if the cache
+// has a name.
+func (c *SharedCache) appendStatus(h http.Header, e CacheStatusEntry) {
+	if c.Name == "" {
+		return
+	}
+	e.Cache = c.Name
+	AppendCacheStatus(h, e)
+}
+
+// fetch calls the wrapped handler, coalescing concurrent calls for the same
+// resource. It reports whether the response was produced for another
+// request. Responses which won't be stored are passed through to w, or
+// discarded if w is nil.
+func (c *SharedCache) fetch(w http.ResponseWriter, r *http.Request, next http.Handler, stale *CacheEntry) (fetchResult, bool) {
+	key := cacheKey(r)
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		f.wg.Wait()
+		if f.failed {
+			// The panic was raised in the request which made the call, so
+			// this one tries the wrapped handler on its own.
+			return c.upstream(w, r, next, stale), false
+		}
+		return f.result, true
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	if c.flights == nil {
+		c.flights = map[string]*flight{}
+	}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	// A panic in the wrapped handler carries on up this request's stack
+	// once the waiters have been released.
+	f.failed = true
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		f.wg.Done()
+	}()
+	f.result = c.upstream(w, r, next, stale)
+	f.failed = false
+	return f.result, false
+}
+
+// upstream calls the wrapped handler and stores its response if allowed.
+// Whether to store the response is decided from its header, so responses
+// which won't be stored, or whose body grows past MaxObjectSize, are passed
+// through to w, or discarded if w is nil, rather than buffered.
+func (c *SharedCache) upstream(w http.ResponseWriter, r *http.Request, next http.Handler, stale *CacheEntry) fetchResult {
+	// Conditional and range headers sent by the client are evaluated by the
+	// cache against the full stored response.
+	req := r.Clone(r.Context())
+	for _, name := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "If-Range", "Range"} {
+		req.Header.Del(name)
+	}
+	if stale != nil {
+		if etag := stale.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := stale.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	requested := c.clock()
+	limit := c.MaxObjectSize
+	if limit == 0 {
+		limit = DefaultMaxObjectSize
+	}
+	rec := newResponseRecorder(w, limit, func(status int, header http.Header) bool {
+		if stale != nil && (status == http.StatusNotModified || status >= 500) {
+			// Needed to update or stand in for the stale response.
+			return true
+		}
+		return storable(r, status, header, true)
+	})
+	next.ServeHTTP(rec, req)
+	if rec.passed {
+		if stale != nil {
+			c.delete(cacheKey(r))
+		}
+		return fetchResult{status: rec.status, passed: true}
+	}
+	res := fetchResult{
+		entry: &CacheEntry{
+			StoredResponse: StoredResponse{
+				StatusCode:   rec.status,
+				Header:       rec.header,
+				RequestTime:  requested,
+				ResponseTime: c.clock(),
+			},
+			Body: rec.body.Bytes(),
+		},
+		status: rec.status,
+	}
+
+	if stale != nil {
+		switch {
+		case rec.status == http.StatusNotModified:
+			res.entry.StatusCode = stale.StatusCode
+			res.entry.Header = updateStoredHeaders(stale.Header, rec.header)
+			res.entry.Body = stale.Body
+		case rec.status >= 500:
+			f := CalculateFreshness(stale.StoredResponse, c.clock(), true)
+			if f.State == CacheStateStaleIfError || f.State == CacheStateStaleWhileRevalidate {
+				res.entry = stale
+				return res
+			}
+		}
+	}
+
+	var vary Vary
+	vary.Parse(fieldValue(res.entry.Header, vary.Name()))
+	res.entry.VaryKey, _ = VaryKey(r, vary)
+	if storable(r, res.entry.StatusCode, res.entry.Header, true) {
+		key := cacheKey(r)
+		if old, ok := c.Store.Get(key); ok {
+			c.untag(key, old)
+		}
+		res.entry.Tags = responseTags(res.entry.Header)
+		c.Store.Set(key, res.entry)
+		c.tag(key, res.entry.Tags)
+		res.stored = true
+	} else if stale != nil {
+		c.delete(cacheKey(r))
+	}
+	return res
+}
+
+// delete removes a stored response, along with its tags.
+func (c *SharedCache) delete(key string) {
+	if entry, ok := c.Store.Get(key); ok {
+		c.untag(key, entry)
+	}
+	c.Store.Delete(key)
+}
+
+// tag records the tags of a stored response, so it can be purged by tag.
+func (c *SharedCache) tag(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tags == nil {
+		c.tags = map[string]map[string]bool{}
+	}
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[string]bool{}
+		}
+		c.tags[tag][key] = true
+	}
+}
+
+// untag forgets the tags of a response which is no longer stored.
+func (c *SharedCache) untag(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeTags(key, entry)
+}
+
+// removeTags drops a key from the index of each of an entry's tags. The
+// cache's lock must be held.
+func (c *SharedCache) removeTags(key string, entry *CacheEntry) {
+	for _, tag := range entry.Tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// evicted forgets the tags of a response evicted from the store, unless the
+// key has been stored again since. Eviction is reported after the store has
+// let go of its lock, so the check and the update are made together under the
+// cache's lock, which tag also takes once the new response is stored.
+func (c *SharedCache) evicted(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.Store.Get(key); ok && current != entry {
+		return
+	}
+	c.removeTags(key, entry)
+}
+
+// Purge removes every stored response tagged, through its Surrogate-Key or
+// Cache-Tag header, with any of the given tags. It returns the number of
+// resources purged.
+func (c *SharedCache) Purge(tags ...string) int {
+	c.mu.Lock()
+	keys := map[string]bool{}
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			keys[key] = true
+		}
+		delete(c.tags, tag)
+	}
+	c.mu.Unlock()
+
+	purged := 0
+	for key := range keys {
+		// The index may be out of date for stores which evict entries
+		// without telling the cache, so only entries which still carry one
+		// of the tags are purged.
+		if entry, ok := c.Store.Get(key); ok && hasAnyTag(entry, tags) {
+			c.delete(key)
+			purged++
+		}
+	}
+	return purged
+}
+
+func hasAnyTag(entry *CacheEntry, tags []string) bool {
+	for _, tag := range tags {
+		for _, t := range entry.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PurgeHandler returns a handler that purges stored responses by tag. Tags
+// are given by a space-separated Surrogate-Key request header or by "tag"
+// query parameters, in a POST or PURGE request. The response body holds the
+// number of resources purged.
+func (c *SharedCache) PurgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != "PURGE" {
+			w.Header().Set("Allow", "POST, PURGE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		var sk SurrogateKey
+		if err := sk.Parse(r.Header.Get(sk.Name())); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tags := append(sk.Keys, r.URL.Query()["tag"]...)
+		if len(tags) == 0 {
+			http.Error(w, "No tags to purge", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "%d\n", c.Purge(tags...))
+	})
+}
+
+// reusable reports whether a response produced for one request may be
+// served to another request for the same resource.
+func reusable(r *http.Request, entry *CacheEntry) bool {
+	var vary Vary
+	vary.Parse(fieldValue(entry.Header, vary.Name()))
+	key, ok := VaryKey(r, vary)
+	return ok && key == entry.VaryKey && storable(r, entry.StatusCode, entry.Header, true)
+}
+
+// serveEntry writes a stored response, answering the request's
+// preconditions.
+func serveEntry(w http.ResponseWriter, r *http.Request, entry *CacheEntry, age time.Duration) {
+	for name, values := range entry.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	a := Age{age}
+	w.Header().Set(a.Name(), a.Value())
+
+	if entry.StatusCode == http.StatusOK {
+		var etag *ETag
+		if tag := (ETag{}); tag.Parse(entry.Header.Get(tag.Name())) == nil {
+			etag = &tag
+		}
+		var lm LastModified
+		lm.Parse(entry.Header.Get(lm.Name()))
+		switch EvaluatePreconditions(r, etag, lm.Time) {
+		case PreconditionNotModified:
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case PreconditionFailed:
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+	}
+	w.WriteHeader(entry.StatusCode)
+	w.Write(entry.Body)
+}
+
+// responseRecorder buffers the response written by a handler, as long as
+// keep approves of its header and its body fits in limit bytes. Otherwise the
+// response is passed through to w, or discarded if w is nil.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	w           http.ResponseWriter
+	limit       int64
+	keep        func(status int, header http.Header) bool
+	passed      bool
+}
+
+func newResponseRecorder(w http.ResponseWriter, limit int64, keep func(status int, header http.Header) bool) *responseRecorder {
+	return &responseRecorder{header: http.Header{}, status: http.StatusOK, w: w, limit: limit, keep: keep}
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+	length, err := strconv.ParseInt(rec.header.Get("Content-Length"), 10, 64)
+	if !rec.keep(status, rec.header) || err == nil && length > rec.limit {
+		rec.pass()
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	if !rec.passed && int64(rec.body.Len()+len(b)) > rec.limit {
+		rec.pass()
+	}
+	if rec.passed {
+		if rec.w == nil {
+			return len(b), nil
+		}
+		return rec.w.Write(b)
+	}
+	return rec.body.Write(b)
+}
+
+// Flush sends what has been written so far to the client, once the response
+// is being passed through. A response being buffered for storage is only
+// written out when complete.
+func (rec *responseRecorder) Flush() {
+	if f, ok := rec.w.(http.Flusher); ok && rec.passed {
+		f.Flush()
+	}
+}
+
+// pass writes the header and what was buffered of the body to w, and has
+// the rest of the body written straight to it.
+func (rec *responseRecorder) pass() {
+	rec.passed = true
+	if rec.w == nil {
+		return
+	}
+	for name, values := range rec.header {
+		rec.w.Header()[name] = values
+	}
+	rec.w.WriteHeader(rec.status)
+	if rec.body.Len() > 0 {
+		rec.w.Write(rec.body.Bytes())
+	}
+	rec.body = bytes.Buffer{}
+}
+
+// statusWriter records the status code written to a ResponseWriter, calling
+// before, if set, just before the header is written.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	before      func(status int)
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+		if w.before != nil {
+			w.before(status)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
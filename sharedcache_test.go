@@ -0,0 +1,374 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSharedCache() (*SharedCache, *cacheClock) {
+	clock := &cacheClock{time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	c := NewSharedCache(10)
+	c.now = clock.Now
+	return c, clock
+}
+
+func serve(h http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestSharedCacheHit(t *testing.T) {
+	var hits int32
+	c, clock := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=0, s-maxage=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+
+	serve(h, "GET", "/", nil)
+	clock.Advance(15 * time.Second)
+	w := serve(h, "GET", "/", nil)
+	if w.Body.String() != "hello" || hits != 1 {
+		t.Errorf("expected a cache hit, got %q after %d requests", w.Body.String(), hits)
+	}
+	if w.Header().Get("Age") != "15" {
+		t.Errorf("expected Age of 15, got %q", w.Header().Get("Age"))
+	}
+
+	w = serve(h, "GET", "/", map[string]string{"If-None-Match": `"v1"`})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || hits != 1 {
+		t.Errorf("expected 304 from the cache, got %d", w.Code)
+	}
+
+	clock.Advance(time.Minute)
+	serve(h, "GET", "/", nil)
+	if hits != 2 {
+		t.Errorf("expected stale response to be refetched")
+	}
+}
+
+func TestSharedCacheNotStored(t *testing.T) {
+	var hits int32
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/authorized":
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+	}))
+	for _, c := range []struct {
+		Path    string
+		Headers map[string]string
+	}{
+		{"/private", nil},
+		{"/no-store", nil},
+		{"/authorized", map[string]string{"Authorization": "Bearer x"}},
+	} {
+		before := atomic.LoadInt32(&hits)
+		serve(h, "GET", c.Path, c.Headers)
+		serve(h, "GET", c.Path, c.Headers)
+		if atomic.LoadInt32(&hits)-before != 2 {
+			t.Errorf("%s: expected response not to be stored", c.Path)
+		}
+	}
+}
+
+func TestSharedCacheVary(t *testing.T) {
+	var hits int32
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Write([]byte(r.Header.Get("Accept-Encoding")))
+	}))
+	serve(h, "GET", "/", map[string]string{"Accept-Encoding": "gzip, br"})
+	if w := serve(h, "GET", "/", map[string]string{"Accept-Encoding": "br, gzip"}); w.Body.String() != "gzip, br" {
+		t.Errorf("expected a cache hit, got %q", w.Body.String())
+	}
+	if w := serve(h, "GET", "/", nil); w.Body.String() != "" || hits != 2 {
+		t.Errorf("expected a cache miss, got %q", w.Body.String())
+	}
+}
+
+func TestSharedCacheCollapse(t *testing.T) {
+	var hits int32
+	started, release := make(chan struct{}), make(chan struct{})
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		w.Write([]byte("hello"))
+	}))
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = serve(h, "GET", "/", nil).Body.String()
+		}(i)
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits != 1 {
+		t.Errorf("expected concurrent misses to collapse, got %d requests", hits)
+	}
+	for i, body := range bodies {
+		if body != "hello" {
+			t.Errorf("%d: unexpected body %q", i, body)
+		}
+	}
+}
+
+func TestSharedCacheCollapsePanic(t *testing.T) {
+	var hits int32
+	started, release := make(chan struct{}), make(chan struct{})
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(started)
+			<-release
+			panic("boom")
+		}
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		w.Write([]byte("hello"))
+	}))
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		serve(h, "GET", "/", nil)
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = serve(h, "GET", "/", nil).Body.String()
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if p := <-panicked; p != "boom" {
+		t.Errorf("expected the panic to reach the first request, got %v", p)
+	}
+	for i, body := range bodies {
+		if body != "hello" {
+			t.Errorf("%d: unexpected body %q", i, body)
+		}
+	}
+}
+
+func TestSharedCacheCollapseUncacheable(t *testing.T) {
+	var hits, active, maxActive int32
+	started, release := make(chan struct{}), make(chan struct{})
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(started)
+			<-release
+		} else {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Header().Set("Cache-Control", "private")
+		w.Write([]byte("hello"))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body := serve(h, "GET", "/", nil).Body.String(); body != "hello" {
+				t.Errorf("unexpected body %q", body)
+			}
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Waiters given an unusable response pass through to the handler
+	// together, rather than one flight at a time.
+	if hits != 10 || maxActive < 2 {
+		t.Errorf("expected uncacheable requests to run concurrently, got %d requests, at most %d at once", hits, maxActive)
+	}
+}
+
+func TestSharedCacheFieldLines(t *testing.T) {
+	var hits int32
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Add("Cache-Control", "s-maxage=60")
+		w.Header().Add("Cache-Control", "private")
+	}))
+	serve(h, "GET", "/", nil)
+	serve(h, "GET", "/", nil)
+	if hits != 2 {
+		t.Errorf("expected directives on separate lines to be combined, got %d requests", hits)
+	}
+
+	res := StoredResponse{StatusCode: 200, Header: http.Header{"Cache-Control": {"max-age=60", "no-cache"}}}
+	if f := CalculateFreshness(res, res.ResponseTime, true); f.State != CacheStateStale {
+		t.Errorf("expected no-cache on a second line to make the response stale, got %+v", f)
+	}
+	cc, field := ResolveCachePolicy(http.Header{"Cdn-Cache-Control": {"max-age=60", "no-store"}}, []string{"CDN"})
+	if field != "CDN-Cache-Control" || !cc.NoStore {
+		t.Errorf("expected targeted directives on separate lines to be combined, got %s %q", field, cc.Value())
+	}
+}
+
+func TestSharedCacheMaxObjectSize(t *testing.T) {
+	var hits int32
+	c, _ := newTestSharedCache()
+	c.Name = "edge"
+	c.MaxObjectSize = 10
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		if r.URL.Path == "/length" {
+			w.Header().Set("Content-Length", "20")
+		}
+		w.Write([]byte(strings.Repeat("a", 5)))
+		w.Write([]byte(strings.Repeat("b", 15)))
+	}))
+
+	for _, path := range []string{"/large", "/length"} {
+		atomic.StoreInt32(&hits, 0)
+		for i := 0; i < 2; i++ {
+			w := serve(h, "GET", path, nil)
+			if w.Body.String() != strings.Repeat("a", 5)+strings.Repeat("b", 15) {
+				t.Errorf("%s: unexpected body %q", path, w.Body.String())
+			}
+			if w.Header().Get("Cache-Status") != "edge;fwd=uri-miss;fwd-status=200" {
+				t.Errorf("%s: unexpected Cache-Status %q", path, w.Header().Get("Cache-Status"))
+			}
+		}
+		if hits != 2 {
+			t.Errorf("%s: expected the response not to be stored, got %d requests", path, hits)
+		}
+	}
+}
+
+func TestSharedCacheFlush(t *testing.T) {
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+	for _, method := range []string{"GET", "POST"} {
+		if w := serve(h, method, "/", nil); !w.Flushed || w.Body.String() != "data: 1\n\n" {
+			t.Errorf("%s: expected the response to be flushed, got %q", method, w.Body.String())
+		}
+	}
+}
+
+func TestSharedCacheMaxStale(t *testing.T) {
+	for _, cc := range []string{"s-maxage=10, proxy-revalidate", "s-maxage=10", "max-age=10, proxy-revalidate"} {
+		var hits int32
+		c, clock := newTestSharedCache()
+		h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", cc)
+		}))
+		serve(h, "GET", "/", nil)
+		clock.Advance(time.Hour)
+		w := serve(h, "GET", "/", map[string]string{"Cache-Control": "max-stale"})
+		if hits != 2 || w.Header().Get("Age") == "3600" {
+			t.Errorf("%q: expected max-stale to be refused, got %d calls and Age %s", cc, hits, w.Header().Get("Age"))
+		}
+	}
+}
+
+func TestSharedCacheStaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	refreshed := make(chan struct{}, 1)
+	c, clock := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=10, stale-while-revalidate=60")
+		if n == 1 {
+			w.Write([]byte("first"))
+			return
+		}
+		w.Write([]byte("second"))
+		refreshed <- struct{}{}
+	}))
+
+	serve(h, "GET", "/", nil)
+	clock.Advance(30 * time.Second)
+	if w := serve(h, "GET", "/", nil); w.Body.String() != "first" {
+		t.Errorf("expected stale response, got %q", w.Body.String())
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatalf("expected a background refresh")
+	}
+	// The refreshed response is stored once the handler returns.
+	for i := 0; i < 100; i++ {
+		if w := serve(h, "GET", "/", nil); w.Body.String() == "second" {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("expected refreshed response to be stored")
+}
+
+func TestSharedCacheInvalidate(t *testing.T) {
+	var hits int32
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "s-maxage=60")
+		}
+	}))
+	serve(h, "GET", "/item", nil)
+	serve(h, "DELETE", "/item", nil)
+	serve(h, "GET", "/item", nil)
+	if hits != 2 {
+		t.Errorf("expected DELETE to invalidate the stored response")
+	}
+}
@@ -0,0 +1,199 @@
+package headers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// A SniffMismatch is a response sent with "X-Content-Type-Options: nosniff"
+// whose declared Content-Type doesn't match its content. Browsers trust the
+// declared type of such responses, and refuse to run scripts or apply styles
+// which aren't declared as such.
+type SniffMismatch struct {
+	// The URL of the request, if known.
+	URL string
+	// The Content-Type of the response.
+	Declared string
+	// The type of the content, as detected from its first bytes.
+	Detected string
+}
+
+func (m *SniffMismatch) Error() string {
+	msg := fmt.Sprintf("nosniff response declared as %q looks like %s", m.Declared, m.Detected)
+	if m.URL != "" {
+		msg = m.URL + ": " + msg
+	}
+	return msg
+}
+
+// CheckSniff checks the start of a response body against its declared
+// Content-Type, when the header sets "X-Content-Type-Options: nosniff". The
+// result is a *SniffMismatch if they disagree, and nil otherwise. Only the
+// first 512 bytes of body are considered.
+//
+// It is meant for tests, as in:
+//
+//	if err := headers.CheckSniff(w.Header(), w.Body.Bytes()); err != nil {
+//		t.Error(err)
+//	}
+func CheckSniff(h http.Header, body []byte) error {
+	if !strings.EqualFold(strings.TrimSpace(h.Get(ContentTypeOptions{}.Name())), "nosniff") || h.Get("Content-Encoding") != "" {
+		return nil
+	}
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	declared := h.Get("Content-Type")
+	detected := detectContentType(body)
+	var ct ContentType
+	if ct.Parse(declared) != nil {
+		if len(body) == 0 {
+			return nil
+		}
+		return &SniffMismatch{Declared: declared, Detected: detected}
+	}
+	if !sniffAgrees(ct, detected) {
+		return &SniffMismatch{Declared: declared, Detected: detected}
+	}
+	return nil
+}
+
+// detectContentType detects the type of a body with http.DetectContentType,
+// and further tells apart JavaScript and CSS among plain text.
+func detectContentType(body []byte) string {
+	detected := http.DetectContentType(body)
+	if !strings.HasPrefix(detected, "text/plain") {
+		return detected
+	}
+	text := bytes.TrimLeft(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case looksLikeScript(text):
+		return "text/javascript"
+	case looksLikeStyle(text):
+		return "text/css"
+	}
+	return detected
+}
+
+var (
+	scriptPrefixes = []string{
+		"'use strict'", `"use strict"`, "(function", "!function", "(() =>",
+		"function ", "function(", "var ", "let ", "const ", "import ", "export ",
+		"async function", "class ", "window.", "document.", "self.",
+	}
+	stylePattern = regexp.MustCompile(`^(@(charset|import|media|font-face|keyframes|layer|supports)\b|:root\s*\{|[\w\s.#*\[\]="':,>~+()-]+\{\s*[\w-]+\s*:)`)
+)
+
+// looksLikeScript reports whether text starts the way JavaScript usually
+// does.
+func looksLikeScript(text []byte) bool {
+	for _, prefix := range scriptPrefixes {
+		if bytes.HasPrefix(text, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeStyle reports whether text starts with a CSS at-rule or a style
+// rule.
+func looksLikeStyle(text []byte) bool {
+	for bytes.HasPrefix(text, []byte("/*")) {
+		end := bytes.Index(text, []byte("*/"))
+		if end < 0 {
+			return false
+		}
+		text = bytes.TrimLeft(text[end+2:], " \t\r\n")
+	}
+	return stylePattern.Match(text)
+}
+
+// javaScriptTypes lists the JavaScript MIME type essences browsers accept
+// for scripts.
+//
+// https://mimesniff.spec.whatwg.org/#javascript-mime-type
+var javaScriptTypes = map[string]bool{
+	"application/ecmascript":   true,
+	"application/javascript":   true,
+	"application/x-ecmascript": true,
+	"application/x-javascript": true,
+	"text/ecmascript":          true,
+	"text/javascript":          true,
+	"text/javascript1.0":       true,
+	"text/javascript1.1":       true,
+	"text/javascript1.2":       true,
+	"text/javascript1.3":       true,
+	"text/javascript1.4":       true,
+	"text/javascript1.5":       true,
+	"text/jscript":             true,
+	"text/livescript":          true,
+	"text/x-ecmascript":        true,
+	"text/x-javascript":        true,
+}
+
+// sniffAgrees reports whether a declared content type is consistent with the
+// detected one. Generic detections, such as plain text, agree with any
+// declared type, except for scripts and styles which must be declared
+// exactly.
+func sniffAgrees(declared ContentType, detected string) bool {
+	var ct ContentType
+	ct.Parse(detected)
+	switch essence := ct.Essence(); {
+	case essence == "application/octet-stream", essence == "text/plain":
+		return true
+	case essence == "text/javascript":
+		return javaScriptTypes[declared.Essence()]
+	case essence == "text/xml":
+		return declared.IsXML()
+	default:
+		return declared.Is(essence)
+	}
+}
+
+// SniffCheck is an http.Handler middleware which checks responses sent with
+// "X-Content-Type-Options: nosniff" against their content, using CheckSniff
+// on the first bytes written, and reports any mismatch. Responses are passed
+// through unchanged.
+type SniffCheck struct {
+	// Called with each mismatch. If nil, responses aren't checked.
+	Report func(*SniffMismatch)
+}
+
+// Handler wraps next with the middleware.
+func (s SniffCheck) Handler(next http.Handler) http.Handler {
+	if s.Report == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&sniffWriter{ResponseWriter: w, url: r.URL.String(), report: s.Report}, r)
+	})
+}
+
+// sniffWriter checks the first write of a response.
+type sniffWriter struct {
+	http.ResponseWriter
+	url     string
+	report  func(*SniffMismatch)
+	checked bool
+}
+
+func (w *sniffWriter) Write(b []byte) (int, error) {
+	if !w.checked && len(b) > 0 {
+		w.checked = true
+		if err := CheckSniff(w.Header(), b); err != nil {
+			m := err.(*SniffMismatch)
+			m.URL = w.url
+			w.report(m)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sniffWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,100 @@
+package headers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckSniff(t *testing.T) {
+	png := "\x89PNG\x0D\x0A\x1A\x0A\x00\x00"
+	for _, c := range []struct {
+		declared, body string
+		detected       string
+	}{
+		{"text/html; charset=utf-8", "<!DOCTYPE html><p>hi", ""},
+		{"application/javascript", "function f() {}", ""},
+		{"text/javascript; charset=utf-8", "\xef\xbb\xbf'use strict';", ""},
+		{"text/css", "body { color: red; }", ""},
+		{"application/json", `{"a": 1}`, ""},
+		{"text/plain", "just some words", ""},
+		{"image/svg+xml", `<?xml version="1.0"?><svg/>`, ""},
+		{"application/zip", "\x00\x01\x02", ""},
+		{"image/png", png, ""},
+		{"", "", ""},
+		{"text/plain", "const x = 1;", "text/javascript"},
+		{"text/html", "(function() {})()", "text/javascript"},
+		{"application/octet-stream", "  import x from './x.js'", "text/javascript"},
+		{"text/plain", "/* reset */\n.button, a:hover { margin: 0 }", "text/css"},
+		{"text/plain", "@import url(a.css);", "text/css"},
+		{"text/css", "<html><body>Not Found</body></html>", "text/html; charset=utf-8"},
+		{"text/html", png, "image/png"},
+		{"", "hello", "text/plain; charset=utf-8"},
+		{"garbage", "hello", "text/plain; charset=utf-8"},
+	} {
+		h := http.Header{"X-Content-Type-Options": {"nosniff"}}
+		if c.declared != "" {
+			h.Set("Content-Type", c.declared)
+		}
+		err := CheckSniff(h, []byte(c.body))
+		if c.detected == "" {
+			if err != nil {
+				t.Errorf("%q %q: unexpected mismatch %s", c.declared, c.body, err)
+			}
+			continue
+		}
+		if m, ok := err.(*SniffMismatch); !ok || m.Detected != c.detected || m.Declared != c.declared {
+			t.Errorf("%q %q: expected %s, got %v", c.declared, c.body, c.detected, err)
+		}
+	}
+
+	h := http.Header{"Content-Type": {"text/plain"}}
+	if err := CheckSniff(h, []byte("const x = 1;")); err != nil {
+		t.Errorf("expected responses without nosniff to be ignored, got %s", err)
+	}
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Encoding", "gzip")
+	if err := CheckSniff(h, []byte("const x = 1;")); err != nil {
+		t.Errorf("expected encoded responses to be ignored, got %s", err)
+	}
+}
+
+func TestSniffCheck(t *testing.T) {
+	var reported []*SniffMismatch
+	h := SniffCheck{Report: func(m *SniffMismatch) {
+		reported = append(reported, m)
+	}}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetContentType(w, ContentType{Type: "text", Subtype: "plain"})
+		w.Write([]byte("body { color: red; }"))
+		w.Write([]byte("const x = 1;"))
+	}))
+
+	w := serve(h, "GET", "/app.css", nil)
+	if w.Body.String() != "body { color: red; }const x = 1;" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+	if len(reported) != 1 || reported[0].URL != "/app.css" || reported[0].Detected != "text/css" {
+		t.Fatalf("unexpected reports %v", reported)
+	}
+	if msg := reported[0].Error(); msg != `/app.css: nosniff response declared as "text/plain" looks like text/css` {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestSniffCheckNoReport(t *testing.T) {
+	h := SniffCheck{}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(*sniffWriter); ok {
+			t.Errorf("expected responses not to be checked without Report")
+		}
+	}))
+	serve(h, "GET", "/", nil)
+}
+
+func TestSniffCheckFlush(t *testing.T) {
+	h := SniffCheck{Report: func(*SniffMismatch) {}}.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+	if w := serve(h, "GET", "/", nil); !w.Flushed {
+		t.Errorf("expected the response to be flushed")
+	}
+}
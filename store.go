@@ -0,0 +1,196 @@
+package headers
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// CacheEntry is a response held by a cache, along with the information
+// needed to decide whether it can be reused for a later request.
+type CacheEntry struct {
+	StoredResponse
+	Body []byte
+	// The secondary cache key of the request that produced the response, as
+	// computed by VaryKey.
+	VaryKey string
+	// The tags the response was stored under, from its Surrogate-Key and
+	// Cache-Tag headers, for purging by tag.
+	Tags []string
+}
+
+// The largest response body the caches in this package store by default.
+const DefaultMaxObjectSize = 1 << 20
+
+// CacheStore is the storage used by the caches in this package. It must be
+// safe for concurrent use. Entries returned by Get are shared and must not be
+// modified.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// MemoryStore is an in-memory CacheStore which holds a limited number of
+// entries, evicting the least recently used entry when it is full.
+type MemoryStore struct {
+	// If set, called with each entry evicted to make room for another. It is
+	// called without the store's lock held, so the key may have been stored
+	// again by then.
+	OnEvict func(key string, entry *CacheEntry)
+
+	capacity int
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryStore returns a MemoryStore holding up to capacity entries.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*memoryItem).entry, true
+}
+
+func (s *MemoryStore) Set(key string, entry *CacheEntry) {
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*memoryItem).entry = entry
+		s.order.MoveToFront(e)
+		s.mu.Unlock()
+		return
+	}
+	s.entries[key] = s.order.PushFront(&memoryItem{key, entry})
+	var evicted []*memoryItem
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		item := oldest.Value.(*memoryItem)
+		delete(s.entries, item.key)
+		evicted = append(evicted, item)
+	}
+	s.mu.Unlock()
+
+	if s.OnEvict != nil {
+		for _, item := range evicted {
+			s.OnEvict(item.key, item.entry)
+		}
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		s.order.Remove(e)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of entries in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+var _ CacheStore = &MemoryStore{}
+
+// cacheKey returns the primary cache key of a request: its target URI.
+func cacheKey(r *http.Request) string {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	return host + r.URL.RequestURI()
+}
+
+// safeMethod reports whether a request method is read-only.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// lookupEntry returns the stored response for a request, if the request
+// matches the stored response's Vary header.
+func lookupEntry(store CacheStore, r *http.Request) (*CacheEntry, bool) {
+	entry, ok := store.Get(cacheKey(r))
+	if !ok {
+		return nil, false
+	}
+	var vary Vary
+	vary.Parse(fieldValue(entry.Header, vary.Name()))
+	if key, ok := VaryKey(r, vary); !ok || key != entry.VaryKey {
+		return nil, false
+	}
+	return entry, true
+}
+
+// storable reports whether a response to a GET request may be stored by a
+// cache, following RFC 9111 section 3.
+func storable(r *http.Request, status int, header http.Header, shared bool) bool {
+	if r.Method != http.MethodGet || !heuristicallyCacheable[status] {
+		return false
+	}
+	// Partial responses would be stored under the key of the full resource.
+	if status == http.StatusPartialContent || r.Header.Get("Range") != "" {
+		return false
+	}
+	if requestCacheControl(r).NoStore {
+		return false
+	}
+	var cc CacheControl
+	if err := cc.Parse(fieldValue(header, cc.Name())); err != nil || cc.NoStore {
+		return false
+	}
+	if shared {
+		if cc.Private && len(cc.PrivateFields) == 0 {
+			return false
+		}
+		// Responses to authenticated requests are only stored by shared
+		// caches when explicitly allowed.
+		if r.Header.Get("Authorization") != "" && !cc.Public && !cc.MustRevalidate && cc.SMaxAge == nil {
+			return false
+		}
+	}
+	var vary Vary
+	vary.Parse(fieldValue(header, vary.Name()))
+	return !vary.Any
+}
+
+// updateStoredHeaders merges the header fields of a 304 (Not Modified)
+// response into those of a stored response.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-3.2
+func updateStoredHeaders(stored, update http.Header) http.Header {
+	merged := stored.Clone()
+	for name, values := range update {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Content-Range", "Transfer-Encoding":
+			continue
+		}
+		merged[name] = values
+	}
+	return merged
+}
@@ -0,0 +1,24 @@
+package headers
+
+import "testing"
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(2)
+	a, b, c := &CacheEntry{Body: []byte("a")}, &CacheEntry{Body: []byte("b")}, &CacheEntry{Body: []byte("c")}
+	s.Set("a", a)
+	s.Set("b", b)
+	if _, ok := s.Get("a"); !ok {
+		t.Fatalf("expected a to be stored")
+	}
+	s.Set("c", c)
+	if _, ok := s.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if e, ok := s.Get("a"); !ok || e != a {
+		t.Errorf("expected a to be kept")
+	}
+	s.Delete("a")
+	if _, ok := s.Get("a"); ok || s.Len() != 1 {
+		t.Errorf("expected a to be deleted")
+	}
+}
@@ -0,0 +1,258 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of Structured Field Values needed by the
+// headers in this package: lists of items whose bare items and parameters are
+// tokens, strings, integers or booleans.
+//
+// https://www.rfc-editor.org/rfc/rfc8941
+
+// An sfItem is a structured field item: a bare value with parameters. Values
+// are string (for sf-string), sfToken, int64 or bool.
+type sfItem struct {
+	value  interface{}
+	params []sfParam
+}
+
+type sfParam struct {
+	key   string
+	value interface{}
+}
+
+type sfToken string
+
+func (it sfItem) param(key string) (interface{}, bool) {
+	for _, p := range it.params {
+		if p.key == key {
+			return p.value, true
+		}
+	}
+	return nil, false
+}
+
+func formatSFList(items []sfItem) string {
+	members := make([]string, len(items))
+	for i, it := range items {
+		var b strings.Builder
+		b.WriteString(formatSFBareItem(it.value))
+		for _, p := range it.params {
+			b.WriteByte(';')
+			b.WriteString(p.key)
+			if v, ok := p.value.(bool); !ok || !v {
+				b.WriteByte('=')
+				b.WriteString(formatSFBareItem(p.value))
+			}
+		}
+		members[i] = b.String()
+	}
+	return strings.Join(members, ", ")
+}
+
+func formatSFBareItem(v interface{}) string {
+	switch v := v.(type) {
+	case sfToken:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		if v {
+			return "?1"
+		}
+		return "?0"
+	case string:
+		return quoteSFString(v)
+	}
+	panic(fmt.Sprintf("unsupported structured field value %T", v))
+}
+
+// quoteSFString serializes an sf-string, which may only hold printable ASCII.
+// Other characters are replaced with '?'.
+func quoteSFString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// sfParser parses structured field values, following the algorithms in
+// section 4.2 of RFC 8941.
+type sfParser struct {
+	input string
+	pos   int
+}
+
+func parseSFList(input string) ([]sfItem, error) {
+	p := &sfParser{input: input}
+	var items []sfItem
+	p.skip(" ")
+	for !p.eof() {
+		it, err := p.item()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+		p.skip(" \t")
+		if p.eof() {
+			break
+		}
+		if p.input[p.pos] != ',' {
+			return nil, fmt.Errorf("expected ',' at offset %d; got %s", p.pos, input)
+		}
+		p.pos++
+		p.skip(" \t")
+		if p.eof() {
+			return nil, fmt.Errorf("trailing ',' in list; got %s", input)
+		}
+	}
+	return items, nil
+}
+
+func (p *sfParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *sfParser) skip(chars string) {
+	for !p.eof() && strings.IndexByte(chars, p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *sfParser) item() (sfItem, error) {
+	v, err := p.bareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+	it := sfItem{value: v}
+	for !p.eof() && p.input[p.pos] == ';' {
+		p.pos++
+		p.skip(" ")
+		key, err := p.key()
+		if err != nil {
+			return sfItem{}, err
+		}
+		var value interface{} = true
+		if !p.eof() && p.input[p.pos] == '=' {
+			p.pos++
+			if value, err = p.bareItem(); err != nil {
+				return sfItem{}, err
+			}
+		}
+		it.params = append(it.params, sfParam{key, value})
+	}
+	return it, nil
+}
+
+func (p *sfParser) key() (string, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.input[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*') {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start || p.input[start] >= '0' && p.input[start] <= '9' {
+		return "", fmt.Errorf("invalid key at offset %d; got %s", start, p.input)
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *sfParser) bareItem() (interface{}, error) {
+	if p.eof() {
+		return nil, fmt.Errorf("unexpected end of structured field; got %s", p.input)
+	}
+	switch c := p.input[p.pos]; {
+	case c == '"':
+		return p.string()
+	case c == '?':
+		if p.pos+1 < len(p.input) && (p.input[p.pos+1] == '0' || p.input[p.pos+1] == '1') {
+			p.pos += 2
+			return p.input[p.pos-1] == '1', nil
+		}
+		return nil, fmt.Errorf("invalid boolean at offset %d; got %s", p.pos, p.input)
+	case c == '-' || c >= '0' && c <= '9':
+		return p.integer()
+	case c == '*' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for !p.eof() && isTokenChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return sfToken(p.input[start:p.pos]), nil
+	}
+	return nil, fmt.Errorf("unsupported structured field value at offset %d; got %s", p.pos, p.input)
+}
+
+func (p *sfParser) string() (string, error) {
+	var b strings.Builder
+	for p.pos++; !p.eof(); p.pos++ {
+		switch c := p.input[p.pos]; {
+		case c == '\\':
+			p.pos++
+			if p.eof() || (p.input[p.pos] != '"' && p.input[p.pos] != '\\') {
+				return "", fmt.Errorf("invalid escape in string; got %s", p.input)
+			}
+			b.WriteByte(p.input[p.pos])
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", fmt.Errorf("invalid character in string; got %s", p.input)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string; got %s", p.input)
+}
+
+func (p *sfParser) integer() (int64, error) {
+	start := p.pos
+	if p.input[p.pos] == '-' {
+		p.pos++
+	}
+	for !p.eof() && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos < len(p.input) && p.input[p.pos] == '.' {
+		return 0, fmt.Errorf("decimals are not supported; got %s", p.input)
+	}
+	n, err := strconv.ParseInt(p.input[start:p.pos], 10, 64)
+	if err != nil || p.pos-start > 16 {
+		return 0, fmt.Errorf("invalid integer at offset %d; got %s", start, p.input)
+	}
+	return n, nil
+}
+
+// isTokenChar reports whether c may appear in a structured field token: a
+// tchar, ':' or '/'.
+func isTokenChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
+
+// isToken reports whether s is a non-empty HTTP token.
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' || s[i] == '/' || !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
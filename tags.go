@@ -0,0 +1,129 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Limits on the size of cache tags, matching those enforced by common CDNs.
+const (
+	// The maximum length of a single tag, in bytes.
+	MaxCacheTagLength = 1024
+	// The maximum length of a tag header value, in bytes.
+	MaxCacheTagHeaderLength = 16 * 1024
+)
+
+// The Surrogate-Key response header assigns tags to a response, so that a
+// cache can later purge every response sharing a tag. Keys are separated by
+// spaces.
+//
+// https://www.fastly.com/documentation/reference/http/http-headers/Surrogate-Key/
+type SurrogateKey struct {
+	Keys []string
+}
+
+func (h SurrogateKey) Name() string {
+	return "Surrogate-Key"
+}
+
+func (h SurrogateKey) Value() string {
+	return strings.Join(h.Keys, " ")
+}
+
+func (h *SurrogateKey) Parse(hdr string) error {
+	keys, err := parseCacheTags(h.Name(), hdr, strings.Fields(hdr))
+	if err != nil {
+		return err
+	}
+	*h = SurrogateKey{keys}
+	return nil
+}
+
+var _ Header = &SurrogateKey{}
+
+// Validate checks the keys against the size limits and character rules of
+// the header.
+func (h SurrogateKey) Validate() error {
+	return validateCacheTags(h.Name(), h.Value(), h.Keys)
+}
+
+// The Cache-Tag response header assigns tags to a response, so that a cache
+// can later purge every response sharing a tag. Tags are separated by commas.
+//
+// https://developers.cloudflare.com/cache/how-to/purge-cache/purge-by-tags/
+type CacheTag struct {
+	Tags []string
+}
+
+func (h CacheTag) Name() string {
+	return "Cache-Tag"
+}
+
+func (h CacheTag) Value() string {
+	return strings.Join(h.Tags, ",")
+}
+
+func (h *CacheTag) Parse(hdr string) error {
+	var tags []string
+	for _, tag := range strings.Split(hdr, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	tags, err := parseCacheTags(h.Name(), hdr, tags)
+	if err != nil {
+		return err
+	}
+	*h = CacheTag{tags}
+	return nil
+}
+
+var _ Header = &CacheTag{}
+
+// Validate checks the tags against the size limits and character rules of
+// the header.
+func (h CacheTag) Validate() error {
+	return validateCacheTags(h.Name(), h.Value(), h.Tags)
+}
+
+func parseCacheTags(name, hdr string, tags []string) ([]string, error) {
+	if err := validateCacheTags(name, hdr, tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// validateCacheTags checks that each tag is made of visible ASCII characters
+// other than the separators, and that the tags fit within the size limits.
+func validateCacheTags(name, value string, tags []string) error {
+	if len(value) > MaxCacheTagHeaderLength {
+		return fmt.Errorf("%s must be at most %d bytes; got %d", name, MaxCacheTagHeaderLength, len(value))
+	}
+	for _, tag := range tags {
+		if tag == "" || len(tag) > MaxCacheTagLength {
+			return fmt.Errorf("%s tags must be between 1 and %d bytes; got %q", name, MaxCacheTagLength, tag)
+		}
+		for i := 0; i < len(tag); i++ {
+			if c := tag[i]; c <= ' ' || c >= 0x7f || c == ',' {
+				return fmt.Errorf("Invalid character in %s tag; got %q", name, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// responseTags returns the tags assigned to a response by its Surrogate-Key
+// and Cache-Tag headers. Invalid headers are ignored.
+func responseTags(h http.Header) []string {
+	var sk SurrogateKey
+	var ct CacheTag
+	var tags []string
+	if sk.Parse(h.Get(sk.Name())) == nil {
+		tags = append(tags, sk.Keys...)
+	}
+	if ct.Parse(h.Get(ct.Name())) == nil {
+		tags = append(tags, ct.Tags...)
+	}
+	return tags
+}
@@ -0,0 +1,154 @@
+package headers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheTags(t *testing.T) {
+	verify(t, []testcase{
+		{&SurrogateKey{}, ""},
+		{&SurrogateKey{[]string{"product-1", "category/shoes"}}, "product-1 category/shoes"},
+		{&CacheTag{}, ""},
+		{&CacheTag{[]string{"product-1", "category/shoes"}}, "product-1,category/shoes"},
+	})
+}
+
+func TestCacheTagsInvalid(t *testing.T) {
+	var sk SurrogateKey
+	if err := sk.Parse("  a   b "); err != nil || len(sk.Keys) != 2 {
+		t.Errorf("expected keys to be split on whitespace, got %q", sk.Keys)
+	}
+	for _, hdr := range []string{
+		"a,b",
+		"café",
+		strings.Repeat("a", MaxCacheTagLength+1),
+		strings.Repeat("a ", MaxCacheTagHeaderLength/2+1),
+	} {
+		if err := sk.Parse(hdr); err == nil {
+			t.Errorf("Surrogate-Key %.20q: expected err", hdr)
+		}
+	}
+	var ct CacheTag
+	if err := ct.Parse("a b"); err == nil {
+		t.Errorf("Cache-Tag: expected err")
+	}
+	if err := (CacheTag{[]string{""}}).Validate(); err == nil {
+		t.Errorf("Cache-Tag: expected empty tag to be invalid")
+	}
+}
+
+func TestSharedCachePurge(t *testing.T) {
+	hits := map[string]int{}
+	c, _ := newTestSharedCache()
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		switch r.URL.Path {
+		case "/products/1":
+			w.Header().Set("Surrogate-Key", "product-1 products")
+		case "/products/2":
+			w.Header().Set("Cache-Tag", "product-2,products")
+		}
+	}))
+	paths := []string{"/products/1", "/products/2", "/about"}
+	for _, path := range paths {
+		serve(h, "GET", path, nil)
+	}
+
+	if n := c.Purge("product-1"); n != 1 {
+		t.Errorf("expected 1 purged resource, got %d", n)
+	}
+	purge := c.PurgeHandler()
+	if w := serve(purge, "GET", "/", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if w := serve(purge, "POST", "/", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if w := serve(purge, "PURGE", "/?tag=products", nil); w.Body.String() != "1\n" {
+		t.Errorf("expected 1 purged resource, got %q", w.Body.String())
+	}
+	if w := serve(purge, "POST", "/", map[string]string{"Surrogate-Key": "products"}); w.Body.String() != "0\n" {
+		t.Errorf("expected nothing to purge, got %q", w.Body.String())
+	}
+
+	for _, path := range paths {
+		serve(h, "GET", path, nil)
+	}
+	for path, expected := range map[string]int{"/products/1": 2, "/products/2": 2, "/about": 1} {
+		if hits[path] != expected {
+			t.Errorf("%s: expected %d requests, got %d", path, expected, hits[path])
+		}
+	}
+}
+
+func TestSharedCachePurgeIndex(t *testing.T) {
+	c := NewSharedCache(2)
+	c.now = (&cacheClock{time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}).Now
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Header().Set("Cache-Control", "s-maxage=60")
+			w.Header().Set("Surrogate-Key", r.URL.Query().Get("tags"))
+		}
+	}))
+	indexed := func() map[string]int {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		sizes := map[string]int{}
+		for tag, keys := range c.tags {
+			sizes[tag] = len(keys)
+		}
+		return sizes
+	}
+
+	serve(h, "GET", "/a?tags=a+all", nil)
+	serve(h, "GET", "/b?tags=b+all", nil)
+	serve(h, "GET", "/c?tags=c+all", nil)
+	// Storing /c evicted /a, along with its tags.
+	if sizes := indexed(); !reflect.DeepEqual(sizes, map[string]int{"b": 1, "c": 1, "all": 2}) {
+		t.Errorf("unexpected index after eviction %v", sizes)
+	}
+
+	serve(h, "DELETE", "/b?tags=b+all", nil)
+	if sizes := indexed(); !reflect.DeepEqual(sizes, map[string]int{"c": 1, "all": 1}) {
+		t.Errorf("unexpected index after invalidation %v", sizes)
+	}
+
+	// A tag of a replaced response no longer purges the new one.
+	c.Store.Delete("example.com/c?tags=c+all")
+	c.tag("example.com/c?tags=c+all", []string{"old"})
+	serve(h, "GET", "/c?tags=c+all", nil)
+	if n := c.Purge("old"); n != 0 {
+		t.Errorf("expected stale tag to purge nothing, got %d", n)
+	}
+	if n := c.Purge("all"); n != 1 {
+		t.Errorf("expected 1 purged resource, got %d", n)
+	}
+	if sizes := indexed(); len(sizes) != 0 {
+		t.Errorf("expected empty index, got %v", sizes)
+	}
+}
+
+func TestSharedCacheEvictRace(t *testing.T) {
+	c := NewSharedCache(2)
+	c.now = (&cacheClock{time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}).Now
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "s-maxage=60")
+		w.Header().Set("Surrogate-Key", "a")
+	}))
+
+	serve(h, "GET", "/a", nil)
+	old, _ := c.Store.Get("example.com/a")
+	// The eviction of the old response is reported after the resource has
+	// been stored again.
+	c.Store.Delete("example.com/a")
+	serve(h, "GET", "/a", nil)
+	c.Store.(*MemoryStore).OnEvict("example.com/a", old)
+	if n := c.Purge("a"); n != 1 {
+		t.Errorf("expected the new response to be purged, got %d", n)
+	}
+}
@@ -0,0 +1,244 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TargetedCacheControl is a Cache-Control header aimed at a specific class of
+// caches, such as CDN-Cache-Control for all CDNs, or a vendor specific field
+// like MyCDN-Cache-Control. A cache that recognizes a targeted field uses it
+// instead of Cache-Control.
+//
+// https://www.rfc-editor.org/rfc/rfc9213
+type TargetedCacheControl struct {
+	// The target of the field, such as "CDN". The field name is the target
+	// followed by "-Cache-Control".
+	Target string
+	CacheControl
+}
+
+// CDNCacheControl returns a CDN-Cache-Control header, which applies to all
+// CDN caches.
+func CDNCacheControl(cc CacheControl) Header {
+	return &TargetedCacheControl{"CDN", cc}
+}
+
+func (h TargetedCacheControl) Name() string {
+	return h.Target + "-Cache-Control"
+}
+
+func (h TargetedCacheControl) Value() string {
+	return h.CacheControl.Value()
+}
+
+func (h *TargetedCacheControl) Parse(hdr string) error {
+	return h.CacheControl.Parse(hdr)
+}
+
+var _ Header = &TargetedCacheControl{}
+
+// The Surrogate-Control response header is used by origin servers to control
+// surrogates, such as CDNs, independently of the Cache-Control header sent to
+// browsers. Directives may be targeted at a single surrogate by appending
+// ";" and its device token.
+//
+// https://www.w3.org/TR/edge-arch/
+type SurrogateControl struct {
+	// The device token of the surrogate the directives are targeted at. When
+	// set before Parse, only untargeted directives and those targeted at this
+	// surrogate are kept.
+	Target string
+	// How long the response may be cached by the surrogate.
+	MaxAge *time.Duration
+	// How long a stale response may be served if the origin server can't be
+	// contacted, written as "+N" after max-age.
+	MaxStale *time.Duration
+	// The response must not be stored by any surrogate.
+	NoStore bool
+	// The response must not be stored by surrogates that are not local to
+	// the origin server, such as CDNs.
+	NoStoreRemote bool
+	// The processing the surrogate should apply, such as "ESI/1.0".
+	Content string
+}
+
+func (h SurrogateControl) Name() string {
+	return "Surrogate-Control"
+}
+
+func (h SurrogateControl) Value() string {
+	var v []string
+	if h.Content != "" {
+		v = append(v, "content=\""+h.Content+"\"")
+	}
+	if h.NoStore {
+		v = append(v, "no-store")
+	}
+	if h.NoStoreRemote {
+		v = append(v, "no-store-remote")
+	}
+	if h.MaxAge != nil {
+		d := deltaDirective("max-age", *h.MaxAge)
+		if h.MaxStale != nil {
+			d += "+" + strconv.FormatInt(int64(*h.MaxStale/time.Second), 10)
+		}
+		v = append(v, d)
+	}
+	if h.Target != "" {
+		for i := range v {
+			v[i] += ";" + h.Target
+		}
+	}
+	return strings.Join(v, ", ")
+}
+
+// Parse reads the directives which apply to the Target device: untargeted
+// directives, overridden by those aimed at the target, wherever they appear
+// in the header.
+func (h *SurrogateControl) Parse(hdr string) error {
+	val := SurrogateControl{Target: h.Target}
+	var untargeted, targeted []string
+	for _, member := range splitList(hdr) {
+		switch d, target := surrogateDirective(member); {
+		case target == "":
+			untargeted = append(untargeted, d)
+		case h.Target != "" && strings.EqualFold(target, h.Target):
+			targeted = append(targeted, d)
+		}
+	}
+	for _, d := range append(untargeted, targeted...) {
+		if err := val.apply(d, hdr); err != nil {
+			return err
+		}
+	}
+	*h = val
+	return nil
+}
+
+// apply sets a single directive.
+func (h *SurrogateControl) apply(d, hdr string) error {
+	name, value := d, ""
+	if i := strings.IndexByte(d, '='); i >= 0 {
+		name, value = d[:i], strings.Trim(d[i+1:], "\"")
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "max-age":
+		age, stale := value, ""
+		if i := strings.IndexByte(value, '+'); i >= 0 {
+			age, stale = value[:i], value[i+1:]
+		}
+		maxAge, err := parseDeltaSeconds(age)
+		if err != nil {
+			return fmt.Errorf("The value for max-age must be a non-negative integer; got %s", hdr)
+		}
+		h.MaxAge = &maxAge
+		if stale != "" {
+			maxStale, err := parseDeltaSeconds(stale)
+			if err != nil {
+				return fmt.Errorf("The stale value for max-age must be a non-negative integer; got %s", hdr)
+			}
+			h.MaxStale = &maxStale
+		}
+	case "no-store":
+		h.NoStore = true
+	case "no-store-remote":
+		h.NoStoreRemote = true
+	case "content":
+		h.Content = value
+	}
+	return nil
+}
+
+var _ Header = &SurrogateControl{}
+
+// surrogateDirective splits a Surrogate-Control list member into the
+// directive and the device token it targets, if any.
+func surrogateDirective(member string) (string, string) {
+	quoted := false
+	for i := len(member) - 1; i >= 0; i-- {
+		switch member[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return strings.TrimSpace(member[:i]), strings.TrimSpace(member[i+1:])
+			}
+		}
+	}
+	return member, ""
+}
+
+// CacheControl returns the equivalent Cache-Control directives, as they
+// apply to a remote surrogate such as a CDN.
+func (h SurrogateControl) CacheControl() CacheControl {
+	return CacheControl{
+		MaxAge:       h.MaxAge,
+		StaleIfError: h.MaxStale,
+		NoStore:      h.NoStore || h.NoStoreRemote,
+	}
+}
+
+// ResolveCachePolicy picks the cache policy that applies to a cache tier,
+// given the targets it recognizes in order of precedence, such as
+// []string{"MyCDN", "CDN"}. The first targeted field present wins, then
+// Surrogate-Control, whose directives are taken for the first of the targets
+// they name, then Cache-Control. It also returns the name of the field used,
+// which is empty if none applies.
+//
+// https://www.rfc-editor.org/rfc/rfc9213#section-2.1
+func ResolveCachePolicy(h http.Header, targets []string) (CacheControl, string) {
+	for _, target := range targets {
+		tcc := TargetedCacheControl{Target: target}
+		// Invalid targeted fields are ignored.
+		if hdr := fieldValue(h, tcc.Name()); hdr != "" && tcc.Parse(hdr) == nil {
+			return tcc.CacheControl, tcc.Name()
+		}
+	}
+
+	var sc SurrogateControl
+	if hdr := fieldValue(h, sc.Name()); hdr != "" {
+		var present []string
+		for _, member := range splitList(hdr) {
+			if _, target := surrogateDirective(member); target != "" {
+				present = append(present, target)
+			}
+		}
+		for _, target := range targets {
+			if containsFold(present, target) {
+				sc.Target = target
+				break
+			}
+		}
+		if sc.Parse(hdr) == nil {
+			return sc.CacheControl(), sc.Name()
+		}
+	}
+
+	var cc CacheControl
+	if hdr := fieldValue(h, cc.Name()); hdr != "" && cc.Parse(hdr) == nil {
+		return cc, cc.Name()
+	}
+	return CacheControl{}, ""
+}
+
+// StripTargeted removes the fields aimed at the given cache targets, as well
+// as Surrogate-Control, before a response is forwarded downstream.
+func StripTargeted(h http.Header, targets []string) {
+	for _, target := range targets {
+		h.Del(TargetedCacheControl{Target: target}.Name())
+	}
+	h.Del(SurrogateControl{}.Name())
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package headers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTargetedCacheControl(t *testing.T) {
+	verify(t, []testcase{
+		{CDNCacheControl(CacheControl{MaxAge: seconds(600)}), "max-age=600"},
+		{&TargetedCacheControl{"MyCDN", CacheControl{NoStore: true}}, "no-store"},
+		{&SurrogateControl{}, ""},
+		{&SurrogateControl{MaxAge: seconds(300)}, "max-age=300"},
+		{&SurrogateControl{MaxAge: seconds(300), MaxStale: seconds(60), Content: "ESI/1.0"},
+			"content=\"ESI/1.0\", max-age=300+60"},
+		{&SurrogateControl{Target: "mycdn", NoStoreRemote: true}, "no-store-remote;mycdn"},
+	})
+	if name := CDNCacheControl(CacheControl{}).Name(); name != "CDN-Cache-Control" {
+		t.Errorf("unexpected name %s", name)
+	}
+}
+
+func TestSurrogateControlTargets(t *testing.T) {
+	hdr := `content="ESI/1.0;x";abc, max-age=60, max-age=3600;mycdn, no-store;other`
+	sc := SurrogateControl{Target: "mycdn"}
+	if err := sc.Parse(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if *sc.MaxAge != time.Hour || sc.NoStore || sc.Content != "" {
+		t.Errorf("unexpected directives for mycdn: %+v", sc)
+	}
+	sc = SurrogateControl{Target: "abc"}
+	if err := sc.Parse(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if *sc.MaxAge != time.Minute || sc.Content != "ESI/1.0;x" {
+		t.Errorf("unexpected directives for abc: %+v", sc)
+	}
+	// Targeted directives win over untargeted ones wherever they appear.
+	sc = SurrogateControl{Target: "mycdn"}
+	if err := sc.Parse("max-age=60;mycdn, max-age=10"); err != nil || *sc.MaxAge != time.Minute {
+		t.Errorf("expected targeted max-age to win: %+v %v", sc, err)
+	}
+	if err := sc.Parse("max-age=1+x"); err == nil {
+		t.Errorf("expected err")
+	}
+}
+
+func TestResolveCachePolicy(t *testing.T) {
+	targets := []string{"MyCDN", "CDN"}
+	for _, c := range []struct {
+		Headers  map[string]string
+		Field    string
+		Expected string
+	}{
+		{map[string]string{}, "", ""},
+		{map[string]string{"Cache-Control": "max-age=60"}, "Cache-Control", "max-age=60"},
+		{map[string]string{"Cache-Control": "max-age=60", "CDN-Cache-Control": "max-age=600"},
+			"CDN-Cache-Control", "max-age=600"},
+		{map[string]string{"Cache-Control": "max-age=60", "CDN-Cache-Control": "max-age=600", "MyCDN-Cache-Control": "no-store"},
+			"MyCDN-Cache-Control", "no-store"},
+		{map[string]string{"Cache-Control": "max-age=60", "MyCDN-Cache-Control": "public, private"},
+			"Cache-Control", "max-age=60"},
+		{map[string]string{"Cache-Control": "max-age=60", "Surrogate-Control": "max-age=300+30, no-store-remote;other"},
+			"Surrogate-Control", "max-age=300, stale-if-error=30"},
+		{map[string]string{"Cache-Control": "max-age=60", "Surrogate-Control": "max-age=300, no-store-remote;mycdn"},
+			"Surrogate-Control", "no-store, max-age=300"},
+		// The caller's precedence decides the target, not the header order.
+		{map[string]string{"Surrogate-Control": "max-age=10;cdn, max-age=20;mycdn"},
+			"Surrogate-Control", "max-age=20"},
+		{map[string]string{"Surrogate-Control": "max-age=60;mycdn, max-age=10"},
+			"Surrogate-Control", "max-age=60"},
+	} {
+		h := http.Header{}
+		for k, v := range c.Headers {
+			h.Set(k, v)
+		}
+		cc, field := ResolveCachePolicy(h, targets)
+		if field != c.Field || cc.Value() != c.Expected {
+			t.Errorf("%v: expected %s %q, got %s %q", c.Headers, c.Field, c.Expected, field, cc.Value())
+		}
+	}
+}
+
+func TestStripTargeted(t *testing.T) {
+	h := http.Header{}
+	h.Set("Cache-Control", "max-age=60")
+	h.Set("CDN-Cache-Control", "max-age=600")
+	h.Set("MyCDN-Cache-Control", "max-age=6000")
+	h.Set("Other-Cache-Control", "no-store")
+	h.Set("Surrogate-Control", "max-age=300")
+	StripTargeted(h, []string{"MyCDN", "CDN"})
+	if len(h) != 2 || h.Get("Cache-Control") == "" || h.Get("Other-Cache-Control") == "" {
+		t.Errorf("unexpected headers %v", h)
+	}
+}
@@ -0,0 +1,237 @@
+package headers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CacheTransport is an http.RoundTripper implementing a private HTTP cache,
+// as used by a single user agent. Fresh stored responses are served without
+// contacting the origin server, stale ones are revalidated with conditional
+// requests, and unsafe requests invalidate what is stored for their target.
+//
+// https://www.rfc-editor.org/rfc/rfc9111
+type CacheTransport struct {
+	// The transport used to contact the origin server. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Where responses are stored.
+	Store CacheStore
+	// The largest response body, in bytes, the transport stores. Larger
+	// responses are returned without being stored. If zero,
+	// DefaultMaxObjectSize is used.
+	MaxObjectSize int64
+
+	now func() time.Time
+}
+
+// NewCacheTransport returns a CacheTransport which stores responses in
+// memory, holding up to capacity of them.
+func NewCacheTransport(capacity int) *CacheTransport {
+	return &CacheTransport{Store: NewMemoryStore(capacity)}
+}
+
+func (t *CacheTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *CacheTransport) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		res, err := t.transport().RoundTrip(req)
+		if err == nil && !safeMethod(req.Method) && res.StatusCode < 400 {
+			t.invalidate(req, res)
+		}
+		return res, err
+	}
+
+	if req.Header.Get("Range") != "" {
+		// Partial responses aren't stored, and stored responses aren't
+		// sliced up, so range requests go straight to the origin server.
+		return t.transport().RoundTrip(req)
+	}
+
+	rcc := requestCacheControl(req)
+
+	entry, ok := lookupEntry(t.Store, req)
+	if !ok {
+		if rcc.OnlyIfCached {
+			return gatewayTimeout(req), nil
+		}
+		return t.fetch(req)
+	}
+
+	f := CalculateFreshness(entry.StoredResponse, t.clock(), false)
+	var cc CacheControl
+	cc.Parse(fieldValue(entry.Header, cc.Name()))
+	if rcc.Accepts(cc, f.Age, f.Lifetime) {
+		return entryResponse(req, entry, f.Age), nil
+	}
+	if rcc.OnlyIfCached {
+		return gatewayTimeout(req), nil
+	}
+	return t.revalidate(req, entry, f)
+}
+
+// fetch forwards a request to the origin server, storing the response if
+// allowed.
+func (t *CacheTransport) fetch(req *http.Request) (*http.Response, error) {
+	requested := t.clock()
+	res, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.keep(req, res, requested)
+}
+
+// keep stores a response received from the origin server, if allowed and
+// its body fits in MaxObjectSize.
+func (t *CacheTransport) keep(req *http.Request, res *http.Response, requested time.Time) (*http.Response, error) {
+	limit := t.MaxObjectSize
+	if limit == 0 {
+		limit = DefaultMaxObjectSize
+	}
+	if !storable(req, res.StatusCode, res.Header, false) || res.ContentLength > limit {
+		return res, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		// Hand back what was read along with the rest of the body.
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	t.store(req, &CacheEntry{
+		StoredResponse: StoredResponse{
+			StatusCode:   res.StatusCode,
+			Header:       res.Header.Clone(),
+			RequestTime:  requested,
+			ResponseTime: t.clock(),
+		},
+		Body: body,
+	})
+	return res, nil
+}
+
+// revalidate sends a conditional request for a stale stored response.
+func (t *CacheTransport) revalidate(req *http.Request, entry *CacheEntry, f Freshness) (*http.Response, error) {
+	cond := req.Clone(req.Context())
+	if etag := entry.Header.Get("ETag"); etag != "" && cond.Header.Get("If-None-Match") == "" {
+		cond.Header.Set("If-None-Match", etag)
+	}
+	if lm := entry.Header.Get("Last-Modified"); lm != "" && cond.Header.Get("If-Modified-Since") == "" {
+		cond.Header.Set("If-Modified-Since", lm)
+	}
+
+	requested := t.clock()
+	res, err := t.transport().RoundTrip(cond)
+	if err != nil || res.StatusCode >= 500 {
+		if f.State == CacheStateStaleIfError || f.State == CacheStateStaleWhileRevalidate {
+			if res != nil {
+				res.Body.Close()
+			}
+			return entryResponse(req, entry, f.Age), nil
+		}
+		return res, err
+	}
+	if res.StatusCode != http.StatusNotModified {
+		return t.keep(req, res, requested)
+	}
+	res.Body.Close()
+
+	updated := &CacheEntry{
+		StoredResponse: StoredResponse{
+			StatusCode:   entry.StatusCode,
+			Header:       updateStoredHeaders(entry.Header, res.Header),
+			RequestTime:  requested,
+			ResponseTime: t.clock(),
+		},
+		Body: entry.Body,
+	}
+	if storable(req, updated.StatusCode, updated.Header, false) {
+		t.store(req, updated)
+	} else {
+		t.Store.Delete(cacheKey(req))
+	}
+	age := CalculateFreshness(updated.StoredResponse, t.clock(), false).Age
+	return entryResponse(req, updated, age), nil
+}
+
+func (t *CacheTransport) store(req *http.Request, entry *CacheEntry) {
+	var vary Vary
+	vary.Parse(fieldValue(entry.Header, vary.Name()))
+	entry.VaryKey, _ = VaryKey(req, vary)
+	t.Store.Set(cacheKey(req), entry)
+}
+
+// invalidate removes the stored responses for the target of an unsafe
+// request, as well as those named by its Location and Content-Location
+// headers when they share the request's origin.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-4.4
+func (t *CacheTransport) invalidate(req *http.Request, res *http.Response) {
+	t.Store.Delete(cacheKey(req))
+	for _, name := range []string{"Location", "Content-Location"} {
+		loc, err := req.URL.Parse(res.Header.Get(name))
+		if err != nil || res.Header.Get(name) == "" || loc.Host != req.URL.Host {
+			continue
+		}
+		t.Store.Delete(loc.Host + loc.RequestURI())
+	}
+}
+
+var _ http.RoundTripper = &CacheTransport{}
+
+// entryResponse builds a response to req from a stored response.
+func entryResponse(req *http.Request, entry *CacheEntry, age time.Duration) *http.Response {
+	header := entry.Header.Clone()
+	a := Age{age}
+	header.Set(a.Name(), a.Value())
+	return &http.Response{
+		Status:        strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
+
+// gatewayTimeout is the response to an only-if-cached request which can't
+// be satisfied from the cache.
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "504 " + http.StatusText(http.StatusGatewayTimeout),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
@@ -0,0 +1,300 @@
+package headers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cacheClock struct {
+	now time.Time
+}
+
+func (c *cacheClock) Now() time.Time {
+	return c.now
+}
+
+func (c *cacheClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestTransport() (*CacheTransport, *cacheClock) {
+	clock := &cacheClock{time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	tr := NewCacheTransport(10)
+	tr.now = clock.Now
+	return tr, clock
+}
+
+func fetch(t *testing.T, client *http.Client, method, url string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+func TestCacheTransportRange(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("hello"))
+	}))
+	defer srv.Close()
+
+	tr, _ := newTestTransport()
+	client := &http.Client{Transport: tr}
+
+	if res, body := fetch(t, client, "GET", srv.URL, map[string]string{"Range": "bytes=0-1"}); res.StatusCode != http.StatusPartialContent || body != "he" {
+		t.Fatalf("unexpected range response %d %q", res.StatusCode, body)
+	}
+	if res, body := fetch(t, client, "GET", srv.URL, nil); res.StatusCode != http.StatusOK || body != "hello" || hits != 2 {
+		t.Errorf("expected the full response from the origin, got %d %q after %d requests", res.StatusCode, body, hits)
+	}
+	// A stored full response isn't used for range requests either.
+	if res, body := fetch(t, client, "GET", srv.URL, map[string]string{"Range": "bytes=1-2"}); res.StatusCode != http.StatusPartialContent || body != "el" || hits != 3 {
+		t.Errorf("unexpected range response %d %q after %d requests", res.StatusCode, body, hits)
+	}
+	if _, body := fetch(t, client, "GET", srv.URL, nil); body != "hello" || hits != 3 {
+		t.Errorf("expected a cache hit, got %q after %d requests", body, hits)
+	}
+}
+
+func TestCacheTransportMaxObjectSize(t *testing.T) {
+	var hits int32
+	body := strings.Repeat("a", 20)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.URL.Path == "/chunked" {
+			// Flushing first leaves the length of the body unknown.
+			w.Write([]byte(body[:5]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[5:]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	tr, _ := newTestTransport()
+	tr.MaxObjectSize = 10
+	client := &http.Client{Transport: tr}
+
+	for _, path := range []string{"/", "/chunked"} {
+		atomic.StoreInt32(&hits, 0)
+		for i := 0; i < 2; i++ {
+			if _, got := fetch(t, client, "GET", srv.URL+path, nil); got != body {
+				t.Errorf("%s: unexpected body %q", path, got)
+			}
+		}
+		if hits != 2 {
+			t.Errorf("%s: expected the response not to be stored, got %d requests", path, hits)
+		}
+	}
+}
+
+func TestCacheTransportFreshHit(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	tr, clock := newTestTransport()
+	client := &http.Client{Transport: tr}
+
+	fetch(t, client, "GET", srv.URL, nil)
+	clock.Advance(10 * time.Second)
+	res, body := fetch(t, client, "GET", srv.URL, nil)
+	if body != "hello" || hits != 1 {
+		t.Errorf("expected a cache hit, got %q after %d requests", body, hits)
+	}
+	if res.Header.Get("Age") != "10" {
+		t.Errorf("expected Age of 10, got %q", res.Header.Get("Age"))
+	}
+
+	fetch(t, client, "GET", srv.URL, map[string]string{"Cache-Control": "no-cache"})
+	if hits != 2 {
+		t.Errorf("expected no-cache request to reach the origin")
+	}
+}
+
+func TestCacheTransportRevalidate(t *testing.T) {
+	var hits, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.Header().Set("X-Revalidated", "yes")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	tr, clock := newTestTransport()
+	client := &http.Client{Transport: tr}
+
+	fetch(t, client, "GET", srv.URL, nil)
+	clock.Advance(2 * time.Minute)
+	res, body := fetch(t, client, "GET", srv.URL, nil)
+	if body != "hello" || res.StatusCode != http.StatusOK || notModified != 1 {
+		t.Fatalf("expected a revalidated response, got %d %q", res.StatusCode, body)
+	}
+	if res.Header.Get("X-Revalidated") != "yes" {
+		t.Errorf("expected stored headers to be updated from the 304 response")
+	}
+
+	clock.Advance(30 * time.Second)
+	fetch(t, client, "GET", srv.URL, nil)
+	if hits != 2 {
+		t.Errorf("expected the revalidated response to be fresh, got %d requests", hits)
+	}
+}
+
+func TestCacheTransportStaleIfError(t *testing.T) {
+	var fail int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10, stale-if-error=60")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	tr, clock := newTestTransport()
+	client := &http.Client{Transport: tr}
+
+	fetch(t, client, "GET", srv.URL, nil)
+	atomic.StoreInt32(&fail, 1)
+	clock.Advance(30 * time.Second)
+	if res, body := fetch(t, client, "GET", srv.URL, nil); res.StatusCode != http.StatusOK || body != "hello" {
+		t.Errorf("expected stale response, got %d %q", res.StatusCode, body)
+	}
+	clock.Advance(time.Minute)
+	if res, _ := fetch(t, client, "GET", srv.URL, nil); res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected error response, got %d", res.StatusCode)
+	}
+}
+
+func TestCacheTransportVary(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewCacheTransport(10)}
+	fetch(t, client, "GET", srv.URL, map[string]string{"Accept-Language": "en, fr"})
+	if _, body := fetch(t, client, "GET", srv.URL, map[string]string{"Accept-Language": "EN,fr"}); body != "en, fr" || hits != 1 {
+		t.Errorf("expected equivalent Accept-Language to hit, got %q", body)
+	}
+	// The order of equally weighted languages decides which one is picked.
+	if _, body := fetch(t, client, "GET", srv.URL, map[string]string{"Accept-Language": "fr, en"}); body != "fr, en" || hits != 2 {
+		t.Errorf("expected reordered Accept-Language to miss, got %q", body)
+	}
+	if _, body := fetch(t, client, "GET", srv.URL, map[string]string{"Accept-Language": "de"}); body != "de" || hits != 3 {
+		t.Errorf("expected different Accept-Language to miss, got %q", body)
+	}
+}
+
+func TestCacheTransportNotStored(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/no-store":
+			w.Header().Set("Cache-Control", "max-age=60, no-store")
+		case "/vary-any":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "*")
+		case "/created":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewCacheTransport(10)}
+	for _, path := range []string{"/no-store", "/vary-any", "/created"} {
+		before := atomic.LoadInt32(&hits)
+		fetch(t, client, "GET", srv.URL+path, nil)
+		fetch(t, client, "GET", srv.URL+path, nil)
+		if atomic.LoadInt32(&hits)-before != 2 {
+			t.Errorf("%s: expected response not to be stored", path)
+		}
+	}
+}
+
+func TestCacheTransportInvalidate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Location", "/other")
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: NewCacheTransport(10),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	fetch(t, client, "GET", srv.URL+"/item", nil)
+	fetch(t, client, "GET", srv.URL+"/other", nil)
+	fetch(t, client, "POST", srv.URL+"/item", nil)
+	fetch(t, client, "GET", srv.URL+"/item", nil)
+	fetch(t, client, "GET", srv.URL+"/other", nil)
+	if hits != 4 {
+		t.Errorf("expected POST to invalidate both responses, got %d requests", hits)
+	}
+}
+
+func TestCacheTransportOnlyIfCached(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to origin")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewCacheTransport(10)}
+	res, _ := fetch(t, client, "GET", srv.URL, map[string]string{"Cache-Control": "only-if-cached"})
+	if res.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", res.StatusCode)
+	}
+	if !strings.HasPrefix(res.Status, "504") {
+		t.Errorf("unexpected status %q", res.Status)
+	}
+}
@@ -0,0 +1,97 @@
+package headers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeUpload(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	png := "\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("\x00", 100)
+	for _, c := range []struct {
+		name     string
+		upload   Upload
+		expected http.Header
+	}{
+		{
+			"image",
+			Upload{Filename: "cat.png", ContentType: "image/png", Content: strings.NewReader(png)},
+			http.Header{
+				"Content-Type":                 {"image/png"},
+				"Content-Disposition":          {`inline; filename="cat.png"`},
+				"Content-Length":               {"108"},
+				"Accept-Ranges":                {"bytes"},
+				"Last-Modified":                {"Thu, 02 Jan 2020 03:04:05 GMT"},
+				"X-Content-Type-Options":       {"nosniff"},
+				"Content-Security-Policy":      {"default-src 'none'; style-src 'unsafe-inline'; sandbox"},
+				"Cross-Origin-Resource-Policy": {"same-origin"},
+			},
+		},
+		{
+			"html disguised as an image",
+			Upload{Filename: "cat.png", ContentType: "image/png", Content: strings.NewReader("<html><script>alert(1)</script>"), ResourcePolicy: ResourcePolicySameSite},
+			http.Header{
+				"Content-Type":                 {"text/html; charset=utf-8"},
+				"Content-Disposition":          {`attachment; filename="cat.png"`},
+				"Content-Length":               {"31"},
+				"Accept-Ranges":                {"bytes"},
+				"Last-Modified":                {"Thu, 02 Jan 2020 03:04:05 GMT"},
+				"X-Content-Type-Options":       {"nosniff"},
+				"Content-Security-Policy":      {"default-src 'none'; style-src 'unsafe-inline'; sandbox"},
+				"Cross-Origin-Resource-Policy": {"same-site"},
+			},
+		},
+		{
+			"text declared as javascript",
+			Upload{Filename: "заметки.js", ContentType: "text/javascript", Content: strings.NewReader("alert(1)"), ResourcePolicy: ResourcePolicyCrossOrigin},
+			http.Header{
+				"Content-Type":                 {"text/plain; charset=utf-8"},
+				"Content-Disposition":          {`inline; filename="_______.js"; filename*=UTF-8''%D0%B7%D0%B0%D0%BC%D0%B5%D1%82%D0%BA%D0%B8.js`},
+				"Content-Length":               {"8"},
+				"Accept-Ranges":                {"bytes"},
+				"Last-Modified":                {"Thu, 02 Jan 2020 03:04:05 GMT"},
+				"X-Content-Type-Options":       {"nosniff"},
+				"Content-Security-Policy":      {"default-src 'none'; style-src 'unsafe-inline'; sandbox"},
+				"Cross-Origin-Resource-Policy": {"cross-origin"},
+			},
+		},
+		{
+			"svg",
+			Upload{Filename: "logo.svg", ContentType: "image/svg+xml", Content: strings.NewReader(`<?xml version="1.0"?><svg onload="alert(1)"/>`)},
+			http.Header{
+				"Content-Type":                 {"text/xml; charset=utf-8"},
+				"Content-Disposition":          {`attachment; filename="logo.svg"`},
+				"Content-Length":               {"45"},
+				"Accept-Ranges":                {"bytes"},
+				"Last-Modified":                {"Thu, 02 Jan 2020 03:04:05 GMT"},
+				"X-Content-Type-Options":       {"nosniff"},
+				"Content-Security-Policy":      {"default-src 'none'; style-src 'unsafe-inline'; sandbox"},
+				"Cross-Origin-Resource-Policy": {"same-origin"},
+			},
+		},
+	} {
+		c.upload.ModTime = modified
+		w := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ServeUpload(w, r, c.upload)
+		}), "GET", "/", nil)
+		if w.Code != http.StatusOK || !reflect.DeepEqual(w.Header(), c.expected) {
+			t.Errorf("%s: unexpected response %d %v", c.name, w.Code, w.Header())
+		}
+	}
+}
+
+func TestServeUploadRange(t *testing.T) {
+	upload := Upload{Filename: "notes.txt", ContentType: "text/plain", Content: strings.NewReader("hello, world")}
+	w := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeUpload(w, r, upload)
+	}), "GET", "/", map[string]string{"Range": "bytes=7-"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "world" {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/plain" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected headers %v", w.Header())
+	}
+}
@@ -0,0 +1,158 @@
+package headers
+
+import (
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// The Vary HTTP response header describes the parts of the request message
+// aside from the method and URL that influenced the content of the response
+// it occurs in. Most often, this is used to create a cache key when content
+// negotiation is in use.
+//
+// https://mdn.io/Vary
+type Vary struct {
+	// The response varies on factors beyond request headers, and caches can
+	// never reuse it without revalidation.
+	Any bool
+	// The request header names that influenced the response.
+	Fields []string
+}
+
+func (h Vary) Name() string {
+	return "Vary"
+}
+
+func (h Vary) Value() string {
+	if h.Any {
+		return "*"
+	}
+	return strings.Join(h.Fields, ", ")
+}
+
+func (h *Vary) Parse(hdr string) error {
+	val := Vary{}
+	val.Add(splitList(hdr)...)
+	*h = val
+	return nil
+}
+
+var _ Header = &Vary{}
+
+// Add merges field names into the header, ignoring names that are already
+// present. Field names are compared case-insensitively. Adding "*" makes the
+// header match any request and drops the listed fields.
+func (h *Vary) Add(fields ...string) {
+	for _, f := range fields {
+		f = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(f))
+		if f == "*" {
+			h.Any = true
+			h.Fields = nil
+		}
+		if h.Any || f == "" || h.Contains(f) {
+			continue
+		}
+		h.Fields = append(h.Fields, f)
+	}
+}
+
+// Contains reports whether the header lists the given field name.
+func (h Vary) Contains(field string) bool {
+	for _, f := range h.Fields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddVary merges field names into the Vary header of a response, so that
+// several middlewares can each add the fields they depend on without
+// producing duplicates.
+func AddVary(w http.ResponseWriter, fields ...string) {
+	var v Vary
+	v.Parse(strings.Join(w.Header().Values(v.Name()), ", "))
+	v.Add(fields...)
+	w.Header().Set(v.Name(), v.Value())
+}
+
+// VaryKey computes the secondary cache key for a request, given the Vary
+// header of a stored response. Two requests with the same key may be served
+// the same stored response. The result is false if the response varies on
+// "*" and can never be reused.
+//
+// Header values are normalized before being compared, so that semantically
+// equivalent requests share a key.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-4.1
+func VaryKey(r *http.Request, v Vary) (string, bool) {
+	if v.Any {
+		return "", false
+	}
+	fields := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		fields[i] = textproto.CanonicalMIMEHeaderKey(f)
+	}
+	sort.Strings(fields)
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 && f == fields[i-1] {
+			continue
+		}
+		normalize, ok := varyNormalizers[f]
+		if !ok {
+			normalize = normalizeList
+		}
+		b.WriteString(f)
+		b.WriteByte(':')
+		b.WriteString(normalize(strings.Join(r.Header.Values(f), ",")))
+		b.WriteByte('\n')
+	}
+	return b.String(), true
+}
+
+// varyNormalizers holds the normalization applied to request headers which
+// are commonly listed in Vary. Headers without an entry have their list
+// members trimmed of whitespace, keeping their case and order.
+var varyNormalizers = map[string]func(string) string{
+	"Accept":          normalizeTokenSet,
+	"Accept-Charset":  normalizeTokenSet,
+	"Accept-Encoding": normalizeTokenSet,
+	"Accept-Language": normalizeTokenList,
+	"Origin":          strings.ToLower,
+}
+
+func normalizeList(value string) string {
+	return strings.Join(splitList(value), ",")
+}
+
+// normalizeTokenSet lower-cases the members of a list, removes whitespace
+// around parameters and sorts them, as their order carries no meaning.
+func normalizeTokenSet(value string) string {
+	members := tokenMembers(value)
+	sort.Strings(members)
+	return strings.Join(members, ",")
+}
+
+// normalizeTokenList is like normalizeTokenSet, but keeps the order of the
+// members, which breaks ties between equal weights in Accept-Language
+// lookup.
+func normalizeTokenList(value string) string {
+	return strings.Join(tokenMembers(value), ",")
+}
+
+// tokenMembers returns the lower-cased members of a list, without whitespace
+// around their parameters.
+func tokenMembers(value string) []string {
+	members := splitList(strings.ToLower(value))
+	for i, m := range members {
+		params := strings.Split(m, ";")
+		for j := range params {
+			params[j] = strings.TrimSpace(params[j])
+		}
+		members[i] = strings.Join(params, ";")
+	}
+	return members
+}
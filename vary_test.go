@@ -0,0 +1,76 @@
+package headers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVary(t *testing.T) {
+	verify(t, []testcase{
+		{&Vary{}, ""},
+		{&Vary{Any: true}, "*"},
+		{&Vary{Fields: []string{"Accept-Encoding", "Origin"}}, "Accept-Encoding, Origin"},
+	})
+}
+
+func TestVaryAdd(t *testing.T) {
+	var v Vary
+	v.Add("Origin", "accept-encoding")
+	v.Add("ORIGIN", "Accept-Encoding", "Accept-Language")
+	if v.Value() != "Origin, Accept-Encoding, Accept-Language" {
+		t.Errorf("unexpected Vary %q", v.Value())
+	}
+	v.Add("*", "Origin")
+	if v.Value() != "*" {
+		t.Errorf("unexpected Vary %q", v.Value())
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Add("Vary", "Origin")
+	w.Header().Add("Vary", "Cookie, origin")
+	AddVary(w, "Origin")
+	AddVary(w, "Accept-Encoding")
+	if v := w.Header().Values("Vary"); len(v) != 1 || v[0] != "Origin, Cookie, Accept-Encoding" {
+		t.Errorf("unexpected Vary %q", v)
+	}
+}
+
+func TestVaryKey(t *testing.T) {
+	vary := Vary{Fields: []string{"Accept-Encoding", "X-Tenant"}}
+	key := func(headers map[string]string) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		k, ok := VaryKey(r, vary)
+		if !ok {
+			t.Fatalf("expected a key")
+		}
+		return k
+	}
+
+	base := key(map[string]string{"Accept-Encoding": "gzip, br", "X-Tenant": "a"})
+	for _, same := range []map[string]string{
+		{"Accept-Encoding": "br,gzip", "X-Tenant": "a"},
+		{"Accept-Encoding": "GZIP , BR", "X-Tenant": " a "},
+	} {
+		if k := key(same); k != base {
+			t.Errorf("%v: expected %q, got %q", same, base, k)
+		}
+	}
+	for _, different := range []map[string]string{
+		{"Accept-Encoding": "gzip", "X-Tenant": "a"},
+		{"Accept-Encoding": "gzip, br", "X-Tenant": "A"},
+		{"Accept-Encoding": "gzip, br"},
+	} {
+		if k := key(different); k == base {
+			t.Errorf("%v: expected a different key than %q", different, base)
+		}
+	}
+
+	if _, ok := VaryKey(httptest.NewRequest("GET", "/", nil), Vary{Any: true}); ok {
+		t.Errorf("Vary: * should never produce a key")
+	}
+}